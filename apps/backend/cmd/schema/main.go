@@ -31,9 +31,12 @@ func main() {
 		}
 	}()
 
-	action := flag.String("action", "", "Action: list, generate, apply, status")
-	name := flag.String("name", "", "Migration name (for generate)")
+	action := flag.String("action", "", "Action: list, generate, apply, status, verify, plan, drift, down, goto, seed, docs, expand, backfill, contract")
+	name := flag.String("name", "", "Migration name (for generate), or column change name (for expand, backfill and contract)")
 	modules := flag.String("modules", "", "Comma-separated module names (empty = all)")
+	env := flag.String("env", "", "Environment to seed, refused unless it is app.environment (for seed)")
+	steps := flag.Int("steps", 1, "Number of migrations to revert (for down)")
+	version := flag.String("version", "", "Target version, 0 reverts every migration (for goto)")
 	flag.Parse()
 
 	switch *action {
@@ -49,14 +52,68 @@ func main() {
 			panic(err)
 		}
 	case "apply":
-		err := migrator.ApplyMigrations()
+		err := migrator.ApplyMigrations(ctx)
 		if err != nil {
 			logger.Fatal(err.Error())
 			panic(err)
 		}
 	case "status":
-		migrator.CheckStatus()
+		if err := migrator.CheckStatus(); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "verify":
+		if err := migrator.VerifyMigrations(); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "plan":
+		if err := migrator.PlanMigrations(); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "drift":
+		if err := migrator.DetectDrift(); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "down":
+		if err := migrator.RevertMigrations(*steps); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "goto":
+		if *version == "" {
+			logger.Fatal("Target version is required for goto action")
+		}
+		if err := migrator.MigrateTo(*version); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "seed":
+		if err := migrator.SeedDatabase(*env, *modules); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "docs":
+		if err := migrator.WriteSchemaDocs(); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "expand":
+		if *name == "" {
+			logger.Fatal("Column change name is required for expand action")
+		}
+		if err := migrator.ExpandColumn(*name); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "backfill":
+		if *name == "" {
+			logger.Fatal("Column change name is required for backfill action")
+		}
+		if err := migrator.BackfillColumn(*name); err != nil {
+			logger.Fatal(err.Error())
+		}
+	case "contract":
+		if *name == "" {
+			logger.Fatal("Column change name is required for contract action")
+		}
+		if err := migrator.ContractColumn(*name); err != nil {
+			logger.Fatal(err.Error())
+		}
 	default:
-		logger.Fatal("Invalid action. Use: list, generate, apply, or status")
+		logger.Fatal("Invalid action. Use: list, generate, apply, status, verify, plan, drift, down, goto, seed, docs, expand, backfill, or contract")
 	}
 }
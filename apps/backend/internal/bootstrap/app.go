@@ -1,12 +1,18 @@
 package bootstrap
 
 import (
+	"time"
+
 	"go.uber.org/fx"
 
 	"github.com/johna210/go-next-flutter/internal/core"
 	"github.com/johna210/go-next-flutter/internal/modules"
 )
 
+// startTimeout bounds starting the app, which may wait for another instance to
+// release the migration lock and then apply migrations
+const startTimeout = 10 * time.Minute
+
 type Application struct {
 	*fx.App
 }
@@ -18,6 +24,11 @@ func NewApp() *Application {
 
 		// Application modules (auth etc..)
 		modules.Modules,
+
+		// HTTP server and scheduled jobs
+		core.ServeModule,
+
+		fx.StartTimeout(startTimeout),
 	)
 
 	return &Application{app}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type actorContextKey struct{}
+
+// Actor identifies who is performing a request and on behalf of which tenant
+type Actor struct {
+	ID       uuid.UUID
+	TenantID string
+}
+
+// WithActor returns ctx carrying actor, typically set by authentication middleware
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx, if any
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorContextKey{}).(Actor)
+	return actor, ok
+}
@@ -0,0 +1,527 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"ariga.io/atlas/sql/migrate"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// expandSuffix and contractSuffix end the descriptions of the migrations
+	// generated for the phases of a column change
+	expandSuffix   = "_expand"
+	contractSuffix = "_contract"
+	// migrationVersionLayout is the version Atlas names migrations with
+	migrationVersionLayout = "20060102150405"
+	// fromPlaceholder stands for the old column in ColumnChange.Expression
+	fromPlaceholder = "{from}"
+)
+
+// Phases of a column change, in the order it goes through them
+const (
+	ChangePlanned     = "planned"
+	ChangeExpandReady = "expand pending"
+	ChangeExpanded    = "expanded"
+	ChangeBackfilling = "backfilling"
+	ChangeBackfilled  = "backfilled"
+	ChangeContracted  = "contracted"
+)
+
+var (
+	// ErrChangeNotExpanded is returned when backfilling a column change whose
+	// expand migration is not applied
+	ErrChangeNotExpanded = errors.New("column change is not expanded")
+	// ErrBackfillIncomplete is returned when applying the contract migration
+	// of a column change that was not backfilled
+	ErrBackfillIncomplete = errors.New("column change is not backfilled")
+)
+
+// ColumnChange renames or retypes a column without downtime, in phases shipped
+// by separate deploys:
+//
+//   - expand adds the new column and a trigger deriving it from the old one on
+//     every write, while the app keeps using the old column
+//   - backfill derives the new column of the existing rows in batches, and
+//     resumes where it stopped when run again
+//   - contract drops the trigger and the old column, once the app uses the new
+//     column and the backfill finished
+//
+// Entities gain the new column with the expand migration, and lose the old one
+// with the contract migration.
+type ColumnChange struct {
+	// Name identifies the change in its migrations and on the command line
+	Name  string
+	Table string
+	From  string
+	To    string
+	// Type is the column type of To. It stays nullable until contracted, as
+	// existing rows only get a value when backfilled.
+	Type string
+	// Expression derives To, with {from} standing for From. To takes the value
+	// of From when empty.
+	Expression string
+	// Key orders the rows the backfill goes through, "id" when empty
+	Key string
+	// BatchSize is how many rows are backfilled per transaction, 1000 when zero
+	BatchSize int
+}
+
+// BackfillRecord tracks the backfill of a column change
+type BackfillRecord struct {
+	Name string `gorm:"size:128;primaryKey" json:"name"`
+	// Cursor is the key of the last row backfilled
+	Cursor    string    `gorm:"size:255;not null;default:''" json:"cursor"`
+	Rows      int64     `gorm:"not null;default:0"           json:"rows"`
+	Done      bool      `gorm:"not null;default:false"       json:"done"`
+	UpdatedAt time.Time `gorm:"not null"                     json:"updated_at"`
+}
+
+func (BackfillRecord) TableName() string { return "schema_backfills" }
+
+// ColumnChangeStatus is the phase a column change reached in the database
+type ColumnChangeStatus struct {
+	Name   string `json:"name"`
+	Module string `json:"module"`
+	Table  string `json:"table"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Phase  string `json:"phase"`
+	// Rows is how many rows were backfilled
+	Rows int64 `json:"rows"`
+}
+
+// ownedChange is a column change and the migrations directory of its module
+type ownedChange struct {
+	ColumnChange
+	module string
+	dir    string
+}
+
+func (c ColumnChange) key() string {
+	if c.Key == "" {
+		return "id"
+	}
+	return c.Key
+}
+
+func (c ColumnChange) batchSize() int {
+	if c.BatchSize <= 0 {
+		return 1000
+	}
+	return c.BatchSize
+}
+
+// derive is the expression of To, with from standing for the old column
+func (c ColumnChange) derive(from string) string {
+	if c.Expression == "" {
+		return from
+	}
+	return strings.ReplaceAll(c.Expression, fromPlaceholder, from)
+}
+
+// triggerName names the dual-write trigger of the change, suffixed for
+// dialects with a trigger per event
+func (c ColumnChange) triggerName(suffix string) string {
+	name := c.Table + "_" + c.Name + "_dual_write"
+	if suffix != "" {
+		name += "_" + suffix
+	}
+	return name
+}
+
+// columnChanges lists the column changes of every module, ordered by module
+func (m *Migrator) columnChanges() []ownedChange {
+	var changes []ownedChange
+	for _, provider := range m.schema.Providers() {
+		changer, ok := provider.(ChangeProvider)
+		if !ok {
+			continue
+		}
+		module, dir := sharedModule, m.migrationsPath()
+		if migrations, ok := provider.(MigrationProvider); ok {
+			module, dir = provider.ModuleName(), migrations.MigrationsDir()
+		}
+		for _, change := range changer.ColumnChanges() {
+			changes = append(changes, ownedChange{ColumnChange: change, module: module, dir: dir})
+		}
+	}
+	return changes
+}
+
+// columnChange finds the column change named name
+func (m *Migrator) columnChange(name string) (ownedChange, error) {
+	for _, change := range m.columnChanges() {
+		if change.Name == name {
+			return change, nil
+		}
+	}
+	return ownedChange{}, fmt.Errorf("no module declares a column change named %s", name)
+}
+
+// quote quotes a table or column name for the database dialect
+func (m *Migrator) quote(name string) string {
+	var b strings.Builder
+	m.db.Dialector.QuoteTo(&b, name)
+	return b.String()
+}
+
+// dualWrite returns the statements creating and dropping the dual-write
+// trigger of change
+func (m *Migrator) dualWrite(change ColumnChange) (create, drop []string, err error) {
+	dialect := m.db.Dialect()
+	if dialect.dualWrite == nil || dialect.autoMigrate {
+		return nil, nil, fmt.Errorf("column changes cannot be expanded on %s databases", dialect.Name)
+	}
+	create, drop = dialect.dualWrite(change, m.quote)
+	return create, drop, nil
+}
+
+// ExpandColumn generates the expand migration of the column change name,
+// adding the new column and its dual-write trigger
+func (m *Migrator) ExpandColumn(name string) error {
+	change, err := m.columnChange(name)
+	if err != nil {
+		return err
+	}
+	if existing, err := changeMigration(change.dir, change.Name+expandSuffix); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("column change %s is already expanded by %s", name, existing.Name())
+	}
+	create, drop, err := m.dualWrite(change.ColumnChange)
+	if err != nil {
+		return err
+	}
+
+	table, to := m.quote(change.Table), m.quote(change.To)
+	up := fmt.Sprintf("-- Expands %s.%s into %s, derived on every write until contracted\nALTER TABLE %s ADD COLUMN %s %s;\n%s\n",
+		change.Table, change.From, change.To, table, to, change.Type, strings.Join(create, "\n"))
+	down := fmt.Sprintf("%s\nALTER TABLE %s DROP COLUMN %s;\n", strings.Join(drop, "\n"), table, to)
+
+	file, err := writeChangeMigration(change.dir, change.Name+expandSuffix, up, down)
+	if err != nil {
+		return err
+	}
+	m.log.Info("Expand migration written, add the new column to the entity",
+		String("module", moduleLabel(change.module)),
+		String("path", file))
+	fmt.Printf("\n Deploy %s, then run the backfill action\n", file)
+	return nil
+}
+
+// ContractColumn generates the contract migration of the column change name,
+// dropping the dual-write trigger and the old column. It refuses to be applied
+// until the backfill finished.
+func (m *Migrator) ContractColumn(name string) error {
+	change, err := m.columnChange(name)
+	if err != nil {
+		return err
+	}
+	if expand, err := changeMigration(change.dir, change.Name+expandSuffix); err != nil {
+		return err
+	} else if expand == nil {
+		return fmt.Errorf("column change %s has no expand migration, run the expand action first", name)
+	}
+	if existing, err := changeMigration(change.dir, change.Name+contractSuffix); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("column change %s is already contracted by %s", name, existing.Name())
+	}
+	_, drop, err := m.dualWrite(change.ColumnChange)
+	if err != nil {
+		return err
+	}
+
+	// Dropping the old column is the point of the migration, the backfill gate
+	// keeps it from losing data
+	up := fmt.Sprintf("-- atlas:nolint %s\n\n-- Contracts %s.%s into %s\n%s\nALTER TABLE %s DROP COLUMN %s;\n",
+		lintDropColumn, change.Table, change.From, change.To, strings.Join(drop, "\n"), m.quote(change.Table), m.quote(change.From))
+	down := fmt.Sprintf("-- Contracting %s.%s drops its data, restore it from a backup to roll back\n", change.Table, change.From)
+
+	file, err := writeChangeMigration(change.dir, change.Name+contractSuffix, up, down)
+	if err != nil {
+		return err
+	}
+	m.log.Info("Contract migration written, remove the old column from the entity",
+		String("module", moduleLabel(change.module)),
+		String("path", file))
+	return nil
+}
+
+// changeMigration finds the migration of dirPath described by description,
+// nil when there is none
+func changeMigration(dirPath, description string) (migrate.File, error) {
+	files, err := migrationFiles(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.Desc() == description {
+			return file, nil
+		}
+	}
+	return nil, nil
+}
+
+// writeChangeMigration writes a migration of dirPath versioned after the
+// latest one and its down script, and rehashes atlas.sum. It returns the path
+// of the migration.
+func writeChangeMigration(dirPath, description, up, down string) (string, error) {
+	version := time.Now().UTC().Truncate(time.Second)
+	files, err := migrationFiles(dirPath)
+	if err != nil {
+		return "", err
+	}
+	if len(files) > 0 {
+		latest, err := time.Parse(migrationVersionLayout, files[len(files)-1].Version())
+		if err == nil && !version.After(latest) {
+			version = latest.Add(time.Second)
+		}
+	}
+
+	name := version.Format(migrationVersionLayout) + "_" + description + ".sql"
+	if err := os.MkdirAll(filepath.Join(dirPath, downMigrationsDir), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create down migrations directory: %w", err)
+	}
+	upPath := filepath.Join(dirPath, name)
+	if err := os.WriteFile(upPath, []byte(up), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write migration: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dirPath, downMigrationsDir, name), []byte(down), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write down migration: %w", err)
+	}
+
+	dir, err := migrate.NewLocalDir(dirPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open migrations directory: %w", err)
+	}
+	sum, err := dir.Checksum()
+	if err != nil {
+		return "", fmt.Errorf("failed to hash migrations: %w", err)
+	}
+	if err := migrate.WriteSumFile(dir, sum); err != nil {
+		return "", fmt.Errorf("failed to write atlas.sum: %w", err)
+	}
+	return upPath, nil
+}
+
+// BackfillColumn backfills the column change name and prints its progress
+func (m *Migrator) BackfillColumn(name string) error {
+	m.log.Info("Backfilling column change", String("change", name))
+	record, err := m.Backfill(context.Background(), name)
+	if err != nil {
+		m.log.Error("Backfill failed", Error(err))
+		return err
+	}
+
+	fmt.Printf("\n Backfilled %d rows, deploy the contract migration once the app uses the new column\n", record.Rows)
+	return nil
+}
+
+// Backfill derives the new column of the rows of the column change name in
+// batches, each in a transaction together with its progress, resuming after
+// the last batch of an earlier run. Writes the dual-write trigger derives
+// meanwhile are left as they are. It returns the finished backfill.
+func (m *Migrator) Backfill(ctx context.Context, name string) (*BackfillRecord, error) {
+	change, err := m.columnChange(name)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedRevisions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !revisionApplied(applied, change.module, change.Name+expandSuffix) {
+		return nil, fmt.Errorf("%w: %s, apply its expand migration first", ErrChangeNotExpanded, name)
+	}
+
+	conn := m.migrationConn(ctx)
+	if !conn.Migrator().HasTable(&BackfillRecord{}) {
+		if err := conn.Migrator().CreateTable(&BackfillRecord{}); err != nil {
+			return nil, fmt.Errorf("failed to create backfills table: %w", err)
+		}
+	}
+	record := BackfillRecord{Name: name}
+	if err := conn.Where(BackfillRecord{Name: name}).FirstOrInit(&record).Error; err != nil {
+		return nil, fmt.Errorf("failed to read backfill of %s: %w", name, err)
+	}
+	if record.Done {
+		return &record, nil
+	}
+
+	var total int64
+	if err := conn.Table(change.Table).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count rows of %s: %w", change.Table, err)
+	}
+
+	key := m.quote(change.key())
+	assignment := gorm.Expr(change.derive(m.quote(change.From)))
+	for !record.Done {
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			query := tx.Table(change.Table).Order(key).Limit(change.batchSize())
+			if record.Cursor != "" {
+				query = query.Where(clause.Expr{SQL: key + " > ?", Vars: []any{record.Cursor}})
+			}
+			var keys []string
+			if err := query.Pluck(change.key(), &keys).Error; err != nil {
+				return fmt.Errorf("failed to read keys of %s: %w", change.Table, err)
+			}
+
+			if len(keys) > 0 {
+				err := tx.Table(change.Table).
+					Where(clause.Expr{SQL: key + " IN ?", Vars: []any{keys}}).
+					Update(change.To, assignment).Error
+				if err != nil {
+					return fmt.Errorf("failed to backfill %s.%s: %w", change.Table, change.To, err)
+				}
+				record.Cursor = keys[len(keys)-1]
+				record.Rows += int64(len(keys))
+			}
+			record.Done = len(keys) < change.batchSize()
+			record.UpdatedAt = time.Now().UTC()
+			if err := tx.Save(&record).Error; err != nil {
+				return fmt.Errorf("failed to record backfill of %s: %w", name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		m.log.Info("Backfilled batch",
+			String("change", name),
+			Int64("rows", record.Rows),
+			Int64("total", total))
+	}
+	return &record, nil
+}
+
+// ColumnChanges reports the phase of every column change
+func (m *Migrator) ColumnChanges(ctx context.Context) ([]ColumnChangeStatus, error) {
+	changes := m.columnChanges()
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	sets, err := m.loadMigrationSets()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedRevisions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backfills, err := m.backfills(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ColumnChangeStatus, 0, len(changes))
+	for _, change := range changes {
+		status := ColumnChangeStatus{
+			Name:   change.Name,
+			Module: change.module,
+			Table:  change.Table,
+			From:   change.From,
+			To:     change.To,
+			Phase:  ChangePlanned,
+		}
+		record, backfilled := backfills[change.Name]
+		switch {
+		case revisionApplied(applied, change.module, change.Name+contractSuffix):
+			status.Phase = ChangeContracted
+		case backfilled && record.Done:
+			status.Phase = ChangeBackfilled
+		case backfilled:
+			status.Phase = ChangeBackfilling
+		case revisionApplied(applied, change.module, change.Name+expandSuffix):
+			status.Phase = ChangeExpanded
+		case setHasMigration(sets, change.module, change.Name+expandSuffix):
+			status.Phase = ChangeExpandReady
+		}
+		status.Rows = record.Rows
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// backfills reads the backfill records keyed by change, none when the
+// backfills table was not created yet
+func (m *Migrator) backfills(ctx context.Context) (map[string]BackfillRecord, error) {
+	conn := m.migrationConn(ctx)
+	if !conn.Migrator().HasTable(&BackfillRecord{}) {
+		return nil, nil
+	}
+	var records []BackfillRecord
+	if err := conn.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read backfills: %w", err)
+	}
+	backfills := make(map[string]BackfillRecord, len(records))
+	for _, record := range records {
+		backfills[record.Name] = record
+	}
+	return backfills, nil
+}
+
+// checkContract refuses the contract migration of a column change that was
+// not backfilled, so the old column is only dropped by a later deploy than
+// the one expanding it
+func (m *Migrator) checkContract(ctx context.Context, mig Migration) error {
+	change, found := ownedChange{}, false
+	for _, c := range m.columnChanges() {
+		if c.module == mig.Module && c.Name+contractSuffix == mig.Description {
+			change, found = c, true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	backfills, err := m.backfills(ctx)
+	if err != nil {
+		return err
+	}
+	if !backfills[change.Name].Done {
+		return fmt.Errorf("%w: %s, run the backfill action before applying %s", ErrBackfillIncomplete, change.Name, mig.path())
+	}
+	return nil
+}
+
+// forgetBackfill removes the backfill of the column change whose expand
+// migration mig reverts, as the column it filled is dropped
+func (m *Migrator) forgetBackfill(conn *gorm.DB, mig Migration) error {
+	name, expand := strings.CutSuffix(mig.Description, expandSuffix)
+	if !expand || !conn.Migrator().HasTable(&BackfillRecord{}) {
+		return nil
+	}
+	if err := conn.Delete(&BackfillRecord{Name: name}).Error; err != nil {
+		return fmt.Errorf("failed to remove backfill of %s: %w", name, err)
+	}
+	return nil
+}
+
+func revisionApplied(applied []SchemaRevision, module, description string) bool {
+	return slices.ContainsFunc(applied, func(rev SchemaRevision) bool {
+		return rev.Module == module && rev.Description == description
+	})
+}
+
+func setHasMigration(sets []migrationSet, module, description string) bool {
+	for _, set := range sets {
+		if set.module == module && slices.ContainsFunc(set.migrations, func(mig Migration) bool {
+			return mig.Description == description
+		}) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// changeProvider declares column changes of the shared migrations directory
+type changeProvider struct {
+	testProvider
+	changes []ColumnChange
+}
+
+func (p changeProvider) ColumnChanges() []ColumnChange { return p.changes }
+
+var renameChange = ColumnChange{
+	Name:       "rename_title",
+	Table:      "books",
+	From:       "title",
+	To:         "name",
+	Type:       "text",
+	Expression: "upper({from})",
+}
+
+// newChangeMigrator returns a SQLite migrator declaring change, with a books
+// table of rows rows
+func newChangeMigrator(t *testing.T, change ColumnChange, rows int) *Migrator {
+	t.Helper()
+	m := newSQLiteMigrator(t)
+	m.config.Database.MigrationsDir = t.TempDir()
+	if err := m.schema.RegisterProvider(changeProvider{testProvider{name: "library"}, []ColumnChange{change}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.db.Exec("CREATE TABLE books (id integer PRIMARY KEY, title text, name text)").Error; err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= rows; id++ {
+		if err := m.db.Exec("INSERT INTO books (id, title) VALUES (?, ?)", id, fmt.Sprintf("book %d", id)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func recordExpand(t *testing.T, m *Migrator, change ColumnChange) {
+	t.Helper()
+	rev := SchemaRevision{Version: "1", Description: change.Name + expandSuffix, Checksum: "a"}
+	if err := m.db.Create(&rev).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// backfilledIDs lists the books whose new column was derived
+func backfilledIDs(t *testing.T, m *Migrator) []int {
+	t.Helper()
+	var ids []int
+	if err := m.db.Table("books").Where("name = upper(title)").Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestBackfillRequiresExpand(t *testing.T) {
+	m := newChangeMigrator(t, renameChange, 3)
+	if _, err := m.Backfill(context.Background(), renameChange.Name); !errors.Is(err, ErrChangeNotExpanded) {
+		t.Fatalf("Backfill() = %v, want %v", err, ErrChangeNotExpanded)
+	}
+	if ids := backfilledIDs(t, m); len(ids) > 0 {
+		t.Fatalf("backfilled %v before expanding", ids)
+	}
+	if _, err := m.Backfill(context.Background(), "unknown"); err == nil {
+		t.Fatal("Backfill() accepted an undeclared column change")
+	}
+}
+
+func TestBackfillBatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      int
+		batchSize int
+	}{
+		{"empty table", 0, 2},
+		{"one partial batch", 3, 5},
+		{"exact batches", 4, 2},
+		{"last batch partial", 5, 2},
+		{"batch of one", 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := renameChange
+			change.BatchSize = tt.batchSize
+			m := newChangeMigrator(t, change, tt.rows)
+			recordExpand(t, m, change)
+
+			record, err := m.Backfill(context.Background(), change.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !record.Done || record.Rows != int64(tt.rows) {
+				t.Fatalf("backfill = %+v, want %d rows done", record, tt.rows)
+			}
+			wantCursor := ""
+			if tt.rows > 0 {
+				wantCursor = fmt.Sprint(tt.rows)
+			}
+			if record.Cursor != wantCursor {
+				t.Errorf("cursor = %q, want %q", record.Cursor, wantCursor)
+			}
+			if ids := backfilledIDs(t, m); len(ids) != tt.rows {
+				t.Fatalf("backfilled rows %v, want all %d", ids, tt.rows)
+			}
+
+			// A finished backfill is not run again
+			if err := m.db.Exec("UPDATE books SET name = NULL").Error; err != nil {
+				t.Fatal(err)
+			}
+			if again, err := m.Backfill(context.Background(), change.Name); err != nil || again.Rows != record.Rows {
+				t.Fatalf("Backfill() again = %+v, %v, want the finished backfill", again, err)
+			}
+			if ids := backfilledIDs(t, m); len(ids) > 0 {
+				t.Fatalf("backfilled %v again", ids)
+			}
+		})
+	}
+}
+
+func TestBackfillResumesFromCursor(t *testing.T) {
+	change := renameChange
+	change.BatchSize = 2
+	m := newChangeMigrator(t, change, 7)
+	recordExpand(t, m, change)
+
+	// An earlier run stopped after the batch ending with row 3
+	if err := m.db.Exec("UPDATE books SET name = upper(title) WHERE id <= 3").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := m.db.AutoMigrate(&BackfillRecord{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.db.Create(&BackfillRecord{Name: change.Name, Cursor: "3", Rows: 3}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Rows the earlier run went through are not backfilled again
+	if err := m.db.Exec("UPDATE books SET title = 'edited' WHERE id = 2").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := m.Backfill(context.Background(), change.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !record.Done || record.Rows != 7 || record.Cursor != "7" {
+		t.Fatalf("backfill = %+v, want 7 rows done up to 7", record)
+	}
+	if got, want := fmt.Sprint(backfilledIDs(t, m)), "[1 3 4 5 6 7]"; got != want {
+		t.Fatalf("backfilled rows %s, want %s", got, want)
+	}
+}
+
+func TestCheckContractRequiresBackfill(t *testing.T) {
+	m := newChangeMigrator(t, renameChange, 3)
+	recordExpand(t, m, renameChange)
+	contract := testMigration("", "2", "b")
+	contract.Description = renameChange.Name + contractSuffix
+
+	if err := m.checkContract(context.Background(), contract); !errors.Is(err, ErrBackfillIncomplete) {
+		t.Fatalf("checkContract() before backfilling = %v, want %v", err, ErrBackfillIncomplete)
+	}
+	other := testMigration("", "2", "b")
+	if err := m.checkContract(context.Background(), other); err != nil {
+		t.Fatalf("checkContract() of another migration = %v", err)
+	}
+	// The contract of a module owning its migrations is another migration
+	owned := contract
+	owned.Module = "library"
+	if err := m.checkContract(context.Background(), owned); err != nil {
+		t.Fatalf("checkContract() of another module = %v", err)
+	}
+
+	if _, err := m.Backfill(context.Background(), renameChange.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.checkContract(context.Background(), contract); err != nil {
+		t.Fatalf("checkContract() after backfilling = %v", err)
+	}
+}
+
+func TestColumnChangeMigrations(t *testing.T) {
+	m := newChangeMigrator(t, renameChange, 0)
+	if err := m.ExpandColumn(renameChange.Name); err == nil {
+		t.Fatal("ExpandColumn() accepted a dialect without dual-write triggers")
+	}
+	dialect := *postgresDialect
+	m.db.dialect = &dialect
+
+	if err := m.ContractColumn(renameChange.Name); err == nil || !strings.Contains(err.Error(), "no expand migration") {
+		t.Fatalf("ContractColumn() before expanding = %v, want it refused", err)
+	}
+	if err := m.ExpandColumn(renameChange.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ExpandColumn(renameChange.Name); err == nil || !strings.Contains(err.Error(), "already expanded") {
+		t.Fatalf("ExpandColumn() again = %v, want it refused", err)
+	}
+	if err := m.ContractColumn(renameChange.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ContractColumn(renameChange.Name); err == nil || !strings.Contains(err.Error(), "already contracted") {
+		t.Fatalf("ContractColumn() again = %v, want it refused", err)
+	}
+
+	// Both migrations load with their down scripts, the contract after the expand
+	migrations, err := m.loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 ||
+		migrations[0].Description != renameChange.Name+expandSuffix ||
+		migrations[1].Description != renameChange.Name+contractSuffix {
+		t.Fatalf("migrations = %v, want the expand then the contract migration", versions(migrations))
+	}
+	for _, mig := range migrations {
+		if mig.down == nil {
+			t.Errorf("%s has no down script", mig.path())
+		}
+	}
+}
@@ -3,7 +3,6 @@ package core
 import (
 	"fmt"
 	"log"
-	"net/url"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -17,6 +16,11 @@ const (
 	DBTypePostgres   = "postgres"
 	DBTypePostgresql = "postgresql"
 	DBTypeMySQL      = "mysql"
+	DBTypeSQLServer  = "sqlserver"
+	DBTypeSQLite     = "sqlite"
+
+	// sqliteMemory as database.dbname keeps a SQLite database in memory
+	sqliteMemory = ":memory:"
 )
 
 func NewConfig() (*Config, error) {
@@ -51,6 +55,40 @@ func NewConfig() (*Config, error) {
 	v.SetDefault("app.port", 8080)
 	v.SetDefault("logger.level", "info")
 	v.SetDefault("database.sslmode", "disable")
+	v.SetDefault("database.tx_max_retries", 3)
+	v.SetDefault("database.replica_policy", "random")
+	v.SetDefault("database.connect_max_attempts", 10)
+	v.SetDefault("database.connect_retry_delay", "500ms")
+	v.SetDefault("database.retry_max_attempts", 3)
+	v.SetDefault("database.retry_base_delay", "50ms")
+	v.SetDefault("database.retry_max_delay", "2s")
+	v.SetDefault("database.statement_timeout", "30s")
+	v.SetDefault("database.slow_query_threshold", "200ms")
+	v.SetDefault("database.lint_large_table_rows", 100000)
+	v.SetDefault("database.migrate_on_start", true)
+	v.SetDefault("database.migration_lock_timeout", "5m")
+	v.SetDefault("metrics.enabled", false)
+	v.SetDefault("metrics.addr", ":9090")
+	v.SetDefault("metrics.path", "/metrics")
+	v.SetDefault("retention.purge_enabled", false)
+	v.SetDefault("retention.archive_ttl", "720h")
+	v.SetDefault("retention.purge_interval", "24h")
+	v.SetDefault("outbox.enabled", false)
+	v.SetDefault("outbox.topics", []string{"*"})
+	v.SetDefault("outbox.sinks", []string{"log"})
+	v.SetDefault("outbox.poll_interval", "2s")
+	v.SetDefault("outbox.batch_size", 100)
+	v.SetDefault("outbox.max_attempts", 10)
+	v.SetDefault("outbox.base_backoff", "5s")
+	v.SetDefault("outbox.max_backoff", "1h")
+	v.SetDefault("outbox.lease_duration", "5m")
+	v.SetDefault("outbox.delivered_ttl", "168h")
+	v.SetDefault("outbox.webhook_timeout", "10s")
+	v.SetDefault("outbox.redis_stream", "outbox")
+	v.SetDefault("seed.demo_password", "")
+	v.SetDefault("audit.enabled", true)
+	// Entities are audited once their topics are listed, e.g. "users.*"
+	v.SetDefault("audit.topics", []string{})
 
 	// 5. Read Base Config File (config.yaml)
 	v.SetConfigName("config")
@@ -93,7 +131,12 @@ func NewConfig() (*Config, error) {
 
 func (c *Config) Validate() error {
 	validate := validator.New()
-	err := validate.StructExcept(c, "Cache", "Database.User", "Database.Password", "Database.DBName")
+	except := []string{"Cache", "Database.User", "Database.Password", "Database.DBName"}
+	if c.Database.Type == DBTypeSQLite {
+		// SQLite is a local file, there is no server to reach
+		except = append(except, "Database.Host", "Database.Port", "Database.SSLMode")
+	}
+	err := validate.StructExcept(c, except...)
 
 	if err != nil {
 		return fmt.Errorf("config validation failed: %w", err)
@@ -107,12 +150,31 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Sinks that need extra settings
+	if c.Outbox.Enabled {
+		for _, sink := range c.Outbox.Sinks {
+			switch {
+			case sink == "webhook" && c.Outbox.WebhookURL == "":
+				return fmt.Errorf("outbox.webhook_url is required by the webhook sink")
+			case sink == "redis" && !c.Cache.Enabled:
+				return fmt.Errorf("the redis outbox sink requires cache.enabled")
+			}
+		}
+	}
+
 	// Validate App.Environment (used directly in exec.Command)
 	if err := utils.IsSafeString(c.App.Environment); err != nil {
 		return fmt.Errorf("invalid app.environment: %w", err)
 	}
 
 	// Validate DSN components (used in GetDSN, which is then used in exec.Command)
+	if c.Database.Type == DBTypeSQLite {
+		// dbname is a file path or :memory:
+		if err := utils.IsSafeDSNComponent(c.Database.DBName); err != nil {
+			return fmt.Errorf("invalid database.dbname: %w", err)
+		}
+		return nil
+	}
 	if err := utils.IsSafeDSNComponent(c.Database.Host); err != nil {
 		return fmt.Errorf("invalid database.host: %w", err)
 	}
@@ -138,26 +200,22 @@ func (c *Config) IsLocal() bool {
 	return c.App.Environment == "local"
 }
 
+// GetDatabaseUrl returns the database URL in the format expected by Atlas
 func (c *Config) GetDatabaseUrl() string {
-	switch c.Database.Type {
-	case DBTypePostgres, DBTypePostgresql:
-		return strings.TrimSpace(c.getPostgreSQLURL())
-	case DBTypeMySQL:
-		return c.getMySQLURL()
-	default:
-		return c.getPostgreSQLURL()
+	dialect, err := c.Dialect()
+	if err != nil {
+		dialect = postgresDialect
 	}
+	return dialect.url(&c.Database)
 }
 
+// GetDSN returns the connection string of the configured database driver
 func (c *Config) GetDSN() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		c.Database.Host,
-		c.Database.Port,
-		c.Database.User,
-		c.Database.Password,
-		c.Database.DBName,
-		c.Database.SSLMode,
-	)
+	dialect, err := c.Dialect()
+	if err != nil {
+		dialect = postgresDialect
+	}
+	return dialect.dsn(&c.Database)
 }
 
 func (c *Config) GetAddr() string {
@@ -168,22 +226,6 @@ func (c *Config) GetAddr() string {
 	return fmt.Sprintf("%s:%d", c.Cache.Host, c.Cache.Port)
 }
 
-func (c *Config) getPostgreSQLURL() string {
-	encodedPassword := url.QueryEscape(c.Database.Password)
-	encodedUser := url.QueryEscape(c.Database.User)
-
-	return fmt.Sprintf("postgresql://%s:%s@%s:%d/%s?sslmode=%s",
-		encodedUser, encodedPassword, c.Database.Host,
-		c.Database.Port, c.Database.DBName, c.Database.SSLMode)
-}
-
-func (c *Config) getMySQLURL() string {
-	encodedPassword := url.QueryEscape(c.Database.Password)
-	return fmt.Sprintf("mysql://%s:%s@%s:%d/%s",
-		c.Database.User, encodedPassword, c.Database.Host,
-		c.Database.Port, c.Database.DBName)
-}
-
 // GetCacheURL provides a standard URL format for cache connection
 func (c *Config) GetCacheURL() string {
 	if !c.Cache.Enabled {
@@ -2,17 +2,26 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"time"
 
-	"gorm.io/driver/postgres"
+	"github.com/prometheus/client_golang/prometheus"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 // Database wraps gorm.DB
 type Database struct {
 	*gorm.DB
+
+	log              Logger
+	dialect          *Dialect
+	replicas         []replica
+	statementTimeout time.Duration
+	retry            RetryPolicy
 }
 
 // The base model for all entities with tenant isolation
@@ -30,35 +39,100 @@ func (b *BaseModel) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
-func NewDatabase(cfg *Config, log Logger) (*Database, error) {
+func NewDatabase(cfg *Config, log Logger, registry *prometheus.Registry) (*Database, error) {
+	dialect, err := cfg.Dialect()
+	if err != nil {
+		return nil, err
+	}
+
 	log.Info("Connecting to database with GORM",
+		String("type", dialect.Name),
 		String("host", cfg.Database.Host),
 		Int("port", int(cfg.Database.Port)),
 		String("database", cfg.Database.DBName),
 	)
 
-	// Configure gorm logger
-	gormLogger := logger.New(
-		&gormLoggerAdapter{log: log},
-		logger.Config{
-			SlowThreshold:             200 * time.Millisecond,
-			LogLevel:                  getGormLogLevel(cfg.Logger.Level),
-			IgnoreRecordNotFoundError: true,
-			Colorful:                  cfg.IsDevelopment(),
+	// Retry the connection while the database may still be starting, but not
+	// failures such as wrong credentials that no retry fixes
+	connectRetry := RetryPolicy{
+		MaxAttempts: cfg.Database.ConnectMaxAttempts,
+		BaseDelay:   cfg.Database.ConnectRetryDelay,
+		MaxDelay:    connectRetryMaxDelay,
+	}
+	var db *gorm.DB
+	err = connectRetry.Do(context.Background(), log, "connect", isConnectRetryable,
+		func(context.Context) error {
+			db, err = connect(cfg, dialect, log)
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	replicas, err := registerReplicas(db, cfg, dialect)
+	if err != nil {
+		return nil, err
+	}
+	if len(replicas) > 0 {
+		log.Info("Routing reads to replicas",
+			Int("replicas", len(replicas)),
+			String("policy", cfg.Database.ReplicaPolicy))
+	}
+
+	metrics, err := newQueryMetrics(registry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
+	if err := db.Use(metrics); err != nil {
+		return nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
+	deadline := &statementDeadline{dialect: dialect, fallback: cfg.Database.StatementTimeout}
+	if err := db.Use(deadline); err != nil {
+		return nil, fmt.Errorf("failed to register statement timeouts: %w", err)
+	}
+	if err := registerPoolMetrics(registry, cfg, db, replicas); err != nil {
+		return nil, fmt.Errorf("failed to register pool metrics: %w", err)
+	}
+
+	log.Info("Successfully connected to database with GORM")
+
+	return &Database{
+		DB:               db,
+		log:              log,
+		dialect:          dialect,
+		replicas:         replicas,
+		statementTimeout: cfg.Database.StatementTimeout,
+		retry: RetryPolicy{
+			MaxAttempts: cfg.Database.RetryMaxAttempts,
+			BaseDelay:   cfg.Database.RetryBaseDelay,
+			MaxDelay:    cfg.Database.RetryMaxDelay,
 		},
-	)
+	}, nil
+}
 
-	// Open Database connection
-	db, err := gorm.Open(postgres.Open(cfg.GetDSN()), &gorm.Config{
-		Logger:                 gormLogger,
+// connect opens the connection pool and verifies it with a ping, closing it
+// again when the database cannot be reached
+func connect(cfg *Config, dialect *Dialect, log Logger) (*gorm.DB, error) {
+	dialector, err := dialect.Open(&cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:                 newQueryLogger(cfg, log),
 		SkipDefaultTransaction: true, // Improve performance
 		PrepareStmt:            true, // Prepared statement cache,
+		DisableAutomaticPing:   true, // Pinged below, after configuring the pool
 		NowFunc: func() time.Time {
 			return time.Now().UTC()
 		},
 	})
-
 	if err != nil {
+		// gorm.Open returns the opened pool along with a failed ping
+		if db != nil {
+			if sqlDB, dbErr := db.DB(); dbErr == nil {
+				_ = sqlDB.Close()
+			}
+		}
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
@@ -72,18 +146,25 @@ func NewDatabase(cfg *Config, log Logger) (*Database, error) {
 	sqlDB.SetMaxOpenConns(int(cfg.Database.MaxOpenConns))
 	sqlDB.SetMaxIdleConns(int(cfg.Database.MaxIdleConns))
 	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	dialect.configurePool(sqlDB, &cfg.Database)
 
 	// Ping to verify connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
+	return db, nil
+}
 
-	log.Info("Successfully connected to database with GORM")
-
-	return &Database{DB: db}, nil
+// isConnectRetryable reports whether connecting may succeed on a later attempt:
+// the failure is transient, the ping timed out, or the host does not resolve
+// yet, as happens while its container starts
+func isConnectRetryable(err error) bool {
+	var dnsErr *net.DNSError
+	return IsTransientError(err) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &dnsErr)
 }
 
 func (db *Database) Health(ctx context.Context) error {
@@ -98,7 +179,7 @@ func (db *Database) Health(ctx context.Context) error {
 		return fmt.Errorf("database health check failed: %w", err)
 	}
 
-	return nil
+	return replicaHealth(ctx, db.replicas)
 }
 
 // Close closes the database connection
@@ -107,7 +188,12 @@ func (db *Database) Close() error {
 	if err != nil {
 		return err
 	}
-	return sqlDB.Close()
+	return errors.Join(sqlDB.Close(), closeReplicas(db.replicas))
+}
+
+// Dialect returns the dialect of the connected database
+func (db *Database) Dialect() *Dialect {
+	return db.dialect
 }
 
 // WithTenant returns a new DB instance scoped to a tenant
@@ -115,17 +201,36 @@ func (db *Database) WithTenant(tenantID string) *gorm.DB {
 	return db.Where("tenant_id = ?", tenantID)
 }
 
-// Transaction executes a function within a database transaction
-func (db *Database) Transaction(ctx context.Context, fn func(*gorm.DB) error) error {
-	return db.DB.WithContext(ctx).Transaction(fn)
-}
-
-type gormLoggerAdapter struct {
-	log Logger
+// Conn returns the transaction carried by ctx, or the pool scoped to ctx.
+// Reads on the pool go to a replica unless ctx is pinned to the primary.
+func (db *Database) Conn(ctx context.Context) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	conn := db.DB.WithContext(ctx)
+	if len(db.replicas) > 0 && readsFromPrimary(ctx) {
+		conn = conn.Clauses(dbresolver.Write)
+	}
+	return conn
 }
 
-func (l *gormLoggerAdapter) Printf(format string, args ...interface{}) {
-	l.log.Info(fmt.Sprintf(format, args...))
+// Transaction executes a function within a database transaction. When ctx
+// already carries a transaction, fn runs in a nested savepoint. Otherwise the
+// transaction is retried on transient failures if ctx opted in with WithRetry.
+func (db *Database) Transaction(ctx context.Context, fn func(*gorm.DB) error) error {
+	err := db.Retry(ctx, "transaction", func(ctx context.Context) error {
+		return db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := db.setLocalStatementTimeout(ctx, tx); err != nil {
+				return err
+			}
+			return fn(tx)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	markWritten(ctx)
+	return nil
 }
 
 func getGormLogLevel(level string) logger.LogLevel {
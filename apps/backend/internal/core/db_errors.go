@@ -0,0 +1,309 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Postgres SQLSTATE codes classified by TranslateDBError and IsTransientError
+const (
+	sqlStateNotNullViolation     = "23502"
+	sqlStateForeignKeyViolation  = "23503"
+	sqlStateUniqueViolation      = "23505"
+	sqlStateCheckViolation       = "23514"
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+	sqlStateLockNotAvailable     = "55P03" // NOWAIT conflicts and lock_timeout
+	sqlStateQueryCanceled        = "57014" // statement_timeout and cancel requests
+	sqlStateAdminShutdown        = "57P01"
+	sqlStateCannotConnect        = "57P03"
+
+	// sqlStateClassConnection is the class of connection failures, e.g. 08006
+	sqlStateClassConnection = "08"
+)
+
+// MySQL error numbers translated by TranslateDBError
+const (
+	mysqlErrDuplicateEntry     = 1062
+	mysqlErrNoReferencedRow    = 1216
+	mysqlErrRowIsReferenced    = 1217
+	mysqlErrRowIsReferenced2   = 1451
+	mysqlErrNoReferencedRow2   = 1452
+	mysqlErrBadNull            = 1048
+	mysqlErrNoDefaultForField  = 1364
+	mysqlErrCheckConstraint    = 3819
+	mysqlErrDeadlock           = 1213
+	mysqlErrLockWaitTimeout    = 1205
+	mysqlErrLockNowaitConflict = 3572
+	mysqlErrQueryInterrupted   = 1317
+	mysqlErrQueryTimeout       = 3024 // max_execution_time exceeded
+)
+
+// SQL Server error numbers translated by TranslateDBError
+const (
+	sqlserverErrUniqueConstraint     = 2627
+	sqlserverErrUniqueIndex          = 2601
+	sqlserverErrConstraintConflict   = 547 // foreign key and check constraints
+	sqlserverErrNotNull              = 515
+	sqlserverErrDeadlock             = 1205
+	sqlserverErrLockTimeout          = 1222
+	sqlserverErrSnapshotUpdateFailed = 3960
+)
+
+// Kinds of DBError, shared by every supported driver
+var (
+	ErrAlreadyExists        = errors.New("record already exists")
+	ErrForeignKeyViolation  = errors.New("referenced record does not exist or is still referenced")
+	ErrNotNullViolation     = errors.New("required value is missing")
+	ErrCheckViolation       = errors.New("value violates a check constraint")
+	ErrSerializationFailure = errors.New("transaction could not be serialized")
+	ErrDeadlock             = errors.New("transaction deadlocked")
+	ErrLockNotAvailable     = errors.New("record is locked by another transaction")
+	ErrQueryTimeout         = errors.New("query timed out")
+	ErrQueryCanceled        = errors.New("query was canceled")
+)
+
+var (
+	// keyDetailPattern extracts the columns from details such as
+	// `Key (email)=(a@b.c) already exists.`
+	keyDetailPattern = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+	// messageColumnPattern extracts the column from MySQL and SQL Server
+	// messages such as `Column 'email' cannot be null`
+	messageColumnPattern = regexp.MustCompile(`(?i)column '([^']+)'`)
+	// messageConstraintPattern extracts the constraint or index from MySQL
+	// and SQL Server messages such as `for key 'users.idx_users_email'`
+	messageConstraintPattern = regexp.MustCompile(`(?:key|index|constraint) ['"]([^'"]+)['"]`)
+	// sqliteColumnPattern extracts the table and column from SQLite messages
+	// such as `UNIQUE constraint failed: users.email`
+	sqliteColumnPattern = regexp.MustCompile(`constraint failed: (\w+)\.(\w+)`)
+)
+
+// sqlServerError is implemented by go-mssqldb errors
+type sqlServerError interface {
+	error
+	SQLErrorNumber() int32
+	SQLErrorMessage() string
+}
+
+// DBError is a driver failure translated to one of the errors above. It matches
+// its Kind (e.g. ErrAlreadyExists) and the original driver error with errors.Is/As.
+type DBError struct {
+	Kind       error
+	Table      string
+	Constraint string
+	Column     string
+	Err        error
+}
+
+func (e *DBError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Kind.Error())
+
+	var details []string
+	if e.Column != "" {
+		details = append(details, "column "+e.Column)
+	}
+	if e.Constraint != "" {
+		details = append(details, "constraint "+e.Constraint)
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+	}
+	return b.String()
+}
+
+func (e *DBError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// TranslateDBError maps constraint, concurrency, locking and timeout failures
+// reported by Postgres, MySQL, SQL Server or SQLite to a *DBError. Other errors are returned unchanged.
+func TranslateDBError(err error) error {
+	var dbErr *DBError
+	if err == nil || errors.As(err, &dbErr) {
+		return err
+	}
+
+	// Drivers return the context error when a query is cut short by the
+	// deadline or cancellation of its context
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &DBError{Kind: ErrQueryTimeout, Err: err}
+	}
+	if errors.Is(err, context.Canceled) {
+		return &DBError{Kind: ErrQueryCanceled, Err: err}
+	}
+
+	if translated := translatePostgresError(err); translated != nil {
+		return translated
+	}
+	if translated := translateMySQLError(err); translated != nil {
+		return translated
+	}
+	if translated := translateSQLServerError(err); translated != nil {
+		return translated
+	}
+	if translated := translateSQLiteError(err); translated != nil {
+		return translated
+	}
+	return err
+}
+
+func translatePostgresError(err error) *DBError {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+
+	var kind error
+	switch pgErr.Code {
+	case sqlStateUniqueViolation:
+		kind = ErrAlreadyExists
+	case sqlStateForeignKeyViolation:
+		kind = ErrForeignKeyViolation
+	case sqlStateNotNullViolation:
+		kind = ErrNotNullViolation
+	case sqlStateCheckViolation:
+		kind = ErrCheckViolation
+	case sqlStateSerializationFailure:
+		kind = ErrSerializationFailure
+	case sqlStateDeadlockDetected:
+		kind = ErrDeadlock
+	case sqlStateLockNotAvailable:
+		kind = ErrLockNotAvailable
+	case sqlStateQueryCanceled:
+		kind = ErrQueryTimeout
+	default:
+		return nil
+	}
+
+	column := pgErr.ColumnName
+	if column == "" {
+		if match := keyDetailPattern.FindStringSubmatch(pgErr.Detail); match != nil {
+			column = match[1]
+		}
+	}
+
+	return &DBError{
+		Kind:       kind,
+		Table:      pgErr.TableName,
+		Constraint: pgErr.ConstraintName,
+		Column:     column,
+		Err:        err,
+	}
+}
+
+func translateMySQLError(err error) *DBError {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return nil
+	}
+
+	var kind error
+	switch myErr.Number {
+	case mysqlErrDuplicateEntry:
+		kind = ErrAlreadyExists
+	case mysqlErrNoReferencedRow, mysqlErrRowIsReferenced,
+		mysqlErrNoReferencedRow2, mysqlErrRowIsReferenced2:
+		kind = ErrForeignKeyViolation
+	case mysqlErrBadNull, mysqlErrNoDefaultForField:
+		kind = ErrNotNullViolation
+	case mysqlErrCheckConstraint:
+		kind = ErrCheckViolation
+	case mysqlErrDeadlock:
+		kind = ErrDeadlock
+	case mysqlErrLockWaitTimeout, mysqlErrLockNowaitConflict:
+		kind = ErrLockNotAvailable
+	case mysqlErrQueryTimeout:
+		kind = ErrQueryTimeout
+	case mysqlErrQueryInterrupted:
+		kind = ErrQueryCanceled
+	default:
+		return nil
+	}
+
+	return messageDBError(kind, myErr.Message, err)
+}
+
+func translateSQLServerError(err error) *DBError {
+	var msErr sqlServerError
+	if !errors.As(err, &msErr) {
+		return nil
+	}
+
+	message := msErr.SQLErrorMessage()
+
+	var kind error
+	switch msErr.SQLErrorNumber() {
+	case sqlserverErrUniqueConstraint, sqlserverErrUniqueIndex:
+		kind = ErrAlreadyExists
+	case sqlserverErrConstraintConflict:
+		kind = ErrCheckViolation
+		if strings.Contains(message, "FOREIGN KEY") || strings.Contains(message, "REFERENCE") {
+			kind = ErrForeignKeyViolation
+		}
+	case sqlserverErrNotNull:
+		kind = ErrNotNullViolation
+	case sqlserverErrDeadlock:
+		kind = ErrDeadlock
+	case sqlserverErrLockTimeout:
+		kind = ErrLockNotAvailable
+	case sqlserverErrSnapshotUpdateFailed:
+		kind = ErrSerializationFailure
+	default:
+		return nil
+	}
+
+	return messageDBError(kind, message, err)
+}
+
+func translateSQLiteError(err error) *DBError {
+	var liteErr sqlite3.Error
+	if !errors.As(err, &liteErr) {
+		return nil
+	}
+
+	var kind error
+	switch {
+	case liteErr.ExtendedCode == sqlite3.ErrConstraintUnique,
+		liteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		kind = ErrAlreadyExists
+	case liteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+		kind = ErrForeignKeyViolation
+	case liteErr.ExtendedCode == sqlite3.ErrConstraintNotNull:
+		kind = ErrNotNullViolation
+	case liteErr.ExtendedCode == sqlite3.ErrConstraintCheck:
+		kind = ErrCheckViolation
+	case liteErr.Code == sqlite3.ErrBusy, liteErr.Code == sqlite3.ErrLocked:
+		kind = ErrLockNotAvailable
+	case liteErr.Code == sqlite3.ErrInterrupt:
+		kind = ErrQueryCanceled
+	default:
+		return nil
+	}
+
+	dbErr := &DBError{Kind: kind, Err: err}
+	if match := sqliteColumnPattern.FindStringSubmatch(liteErr.Error()); match != nil {
+		dbErr.Table, dbErr.Column = match[1], match[2]
+	}
+	return dbErr
+}
+
+// messageDBError builds a DBError from a driver that only reports the
+// offending column and constraint in its message
+func messageDBError(kind error, message string, err error) *DBError {
+	dbErr := &DBError{Kind: kind, Err: err}
+	if match := messageColumnPattern.FindStringSubmatch(message); match != nil {
+		dbErr.Column = match[1]
+	}
+	if match := messageConstraintPattern.FindStringSubmatch(message); match != nil {
+		dbErr.Constraint = match[1]
+	}
+	return dbErr
+}
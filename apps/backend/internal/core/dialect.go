@@ -0,0 +1,446 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Dialect describes how to connect to one database type and how to adapt the
+// Postgres column types declared by entity tags to it.
+type Dialect struct {
+	// Name is the GORM dialector and Atlas driver name
+	Name string
+
+	// driverName is the database/sql driver used to open replica pools
+	driverName string
+	// connect opens the pool itself when the driver needs configuring
+	// beyond its DSN
+	connect   func(dsn string) (*sql.DB, error)
+	dsn       func(cfg *DatabaseConfig) string
+	url       func(cfg *DatabaseConfig) string
+	dialector func(dsn string, conn gorm.ConnPool) gorm.Dialector
+
+	// columnTypes and defaults replace Postgres column types and default
+	// expressions used in entity tags
+	columnTypes map[string]string
+	defaults    map[string]string
+	// indexedStringSize bounds indexed string columns without a size, as
+	// unbounded text columns cannot be indexed
+	indexedStringSize int
+	// schemaPreamble is written before the generated schema
+	schemaPreamble string
+	// devURL is the Atlas dev database used to compute migration diffs
+	devURL string
+	// autoMigrate creates the schema with GORM AutoMigrate instead of
+	// applying the migrations directory
+	autoMigrate bool
+	// embeddedMigrations is set for the database the embedded migrations,
+	// those modules embed and schema/schema.sql are written for. Other
+	// databases need database.migrations_dir.
+	embeddedMigrations bool
+	// transactionalDDL is set when schema changes can be rolled back, so a
+	// failed migration leaves the schema untouched
+	transactionalDDL bool
+	// tableRowsQuery estimates the rows of a table, to flag index builds that
+	// lock large tables. Empty for dialects that cannot build indexes without
+	// blocking writes.
+	tableRowsQuery string
+	// lock serializes migrations across instances. Zero for dialects whose
+	// migrations need no lock.
+	lock lockQueries
+	// drift inspects the live schema. Zero for dialects where schema drift
+	// cannot be detected.
+	drift driftQueries
+	// dualWrite returns the statements creating the trigger that derives the
+	// new column of change from the old one on every write, and those dropping
+	// it. Nil for dialects without expand/contract column changes.
+	dualWrite func(change ColumnChange, quote func(string) string) (create, drop []string)
+}
+
+// lockQueries take and release the session lock named by their parameter
+type lockQueries struct {
+	// try takes the lock without waiting, returning whether it was acquired
+	try    string
+	unlock string
+}
+
+// driftQueries replay DDL in an isolated schema and list what it created
+type driftQueries struct {
+	// isolateSchema makes the transaction tx create its tables in a new
+	// schema, so DDL can be replayed and inspected there and rolled back
+	isolateSchema func(tx *gorm.DB, name string) error
+	// columns and indexes list the table_name and name of the columns and
+	// indexes of the current schema
+	columns string
+	indexes string
+}
+
+var postgresDialect = &Dialect{
+	Name:       DBTypePostgres,
+	driverName: "pgx",
+	connect:    openPostgres,
+	dsn: func(cfg *DatabaseConfig) string {
+		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+	},
+	url: func(cfg *DatabaseConfig) string {
+		return fmt.Sprintf("postgresql://%s:%s@%s:%d/%s?sslmode=%s",
+			url.QueryEscape(cfg.User), url.QueryEscape(cfg.Password), cfg.Host,
+			cfg.Port, cfg.DBName, cfg.SSLMode)
+	},
+	dialector: func(dsn string, conn gorm.ConnPool) gorm.Dialector {
+		return postgres.New(postgres.Config{DSN: dsn, Conn: conn})
+	},
+	schemaPreamble:     "CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\";\n",
+	devURL:             "docker://postgres?search_path=public",
+	embeddedMigrations: true,
+	transactionalDDL:   true,
+	// reltuples is -1 until the table is first analyzed
+	tableRowsQuery: "SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass(?)",
+	lock: lockQueries{
+		try:    "SELECT pg_try_advisory_lock(hashtext($1))",
+		unlock: "SELECT pg_advisory_unlock(hashtext($1))",
+	},
+	drift: driftQueries{
+		isolateSchema: isolatePostgresSchema,
+		columns: `SELECT c.table_name, c.column_name AS name
+			FROM information_schema.columns c
+			JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+			WHERE t.table_schema = current_schema() AND t.table_type = 'BASE TABLE'`,
+		indexes: `SELECT tablename AS table_name, indexname AS name
+			FROM pg_indexes WHERE schemaname = current_schema()`,
+	},
+	dualWrite: postgresDualWrite,
+}
+
+var mysqlDialect = &Dialect{
+	Name:       DBTypeMySQL,
+	driverName: "mysql",
+	dsn:        mysqlDSN,
+	url: func(cfg *DatabaseConfig) string {
+		u := url.URL{
+			Scheme: "mysql",
+			User:   url.UserPassword(cfg.User, cfg.Password),
+			Host:   net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+			Path:   "/" + cfg.DBName,
+		}
+		return u.String()
+	},
+	dialector: func(dsn string, conn gorm.ConnPool) gorm.Dialector {
+		return mysql.New(mysql.Config{DSN: dsn, Conn: conn})
+	},
+	columnTypes: map[string]string{
+		"uuid":  "char(36)",
+		"jsonb": "json",
+	},
+	defaults: map[string]string{
+		"uuid_generate_v4()": "(uuid())",
+		// datetime(3) columns only accept a default of the same precision
+		"CURRENT_TIMESTAMP": "CURRENT_TIMESTAMP(3)",
+	},
+	indexedStringSize: 191,
+	devURL:            "docker://mysql/8/dev",
+	lock: lockQueries{
+		try:    "SELECT GET_LOCK(?, 0) = 1",
+		unlock: "SELECT RELEASE_LOCK(?)",
+	},
+	dualWrite: mysqlDualWrite,
+}
+
+var sqlserverDialect = &Dialect{
+	Name:       DBTypeSQLServer,
+	driverName: "sqlserver",
+	dsn:        sqlserverURL,
+	url:        sqlserverURL,
+	dialector: func(dsn string, conn gorm.ConnPool) gorm.Dialector {
+		return sqlserver.New(sqlserver.Config{DSN: dsn, Conn: conn})
+	},
+	// uniqueidentifier scans in mixed byte order, so ids are stored as text
+	columnTypes: map[string]string{
+		"uuid":  "char(36)",
+		"jsonb": "nvarchar(max)",
+	},
+	defaults: map[string]string{
+		"uuid_generate_v4()": "LOWER(CONVERT(char(36), NEWID()))",
+	},
+	indexedStringSize: 256,
+	devURL:            "docker://sqlserver/2022-latest/dev",
+	transactionalDDL:  true,
+	lock: lockQueries{
+		// sp_getapplock returns 0 or 1 when granted and a negative code otherwise
+		try: `DECLARE @result int;
+			EXEC @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0;
+			SELECT CAST(CASE WHEN @result >= 0 THEN 1 ELSE 0 END AS bit)`,
+		unlock: "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'",
+	},
+}
+
+// sqliteDialect keeps the database in the file named by dbname, or in memory
+// for ":memory:". Its schema is created with AutoMigrate instead of Atlas.
+var sqliteDialect = &Dialect{
+	Name:       DBTypeSQLite,
+	driverName: sqlite.DriverName,
+	dsn:        sqliteDSN,
+	url: func(cfg *DatabaseConfig) string {
+		if cfg.DBName == sqliteMemory {
+			return "sqlite://dev?mode=memory"
+		}
+		return "sqlite://" + cfg.DBName
+	},
+	dialector: func(dsn string, conn gorm.ConnPool) gorm.Dialector {
+		return sqlite.New(sqlite.Config{DSN: dsn, Conn: conn})
+	},
+	columnTypes: map[string]string{
+		"uuid":  "text",
+		"jsonb": "text",
+	},
+	// ids are assigned by model.BaseModel before insert
+	defaults: map[string]string{
+		"uuid_generate_v4()": "",
+	},
+	devURL:           "sqlite://dev?mode=memory",
+	autoMigrate:      true,
+	transactionalDDL: true,
+}
+
+// DialectFor returns the dialect of a configured database type
+func DialectFor(dbType string) (*Dialect, error) {
+	switch dbType {
+	case DBTypePostgres, DBTypePostgresql:
+		return postgresDialect, nil
+	case DBTypeMySQL:
+		return mysqlDialect, nil
+	case DBTypeSQLServer:
+		return sqlserverDialect, nil
+	case DBTypeSQLite:
+		return sqliteDialect, nil
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+}
+
+// Dialect returns the dialect of the configured database
+func (c *Config) Dialect() (*Dialect, error) {
+	return DialectFor(c.Database.Type)
+}
+
+// Open returns the GORM dialector connecting to the configured database
+func (d *Dialect) Open(cfg *DatabaseConfig) (gorm.Dialector, error) {
+	dsn := d.dsn(cfg)
+	if d.connect == nil {
+		return d.dialector(dsn, nil), nil
+	}
+	sqlDB, err := d.connect(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return d.dialector("", sqlDB), nil
+}
+
+// openDB opens a connection pool to dsn
+func (d *Dialect) openDB(dsn string) (*sql.DB, error) {
+	if d.connect != nil {
+		return d.connect(dsn)
+	}
+	return sql.Open(d.driverName, dsn)
+}
+
+// OpenConn returns the GORM dialector using an already opened pool
+func (d *Dialect) OpenConn(conn gorm.ConnPool) gorm.Dialector {
+	return d.dialector("", conn)
+}
+
+// AdaptSchema rewrites the parsed schemas of models cached by db so that the
+// DDL GORM generates from them is valid for d.
+func (d *Dialect) AdaptSchema(db *gorm.DB, models ...any) error {
+	if d.columnTypes == nil && d.defaults == nil && d.indexedStringSize == 0 {
+		return nil
+	}
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse schema: %w", err)
+		}
+
+		indexed := make(map[string]bool)
+		for _, idx := range stmt.Schema.ParseIndexes() {
+			for _, opt := range idx.Fields {
+				indexed[opt.DBName] = true
+			}
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if dataType, ok := d.columnTypes[string(field.DataType)]; ok {
+				field.DataType = schema.DataType(dataType)
+			}
+			if value, ok := d.defaults[field.DefaultValue]; ok && field.DefaultValueInterface == nil {
+				field.DefaultValue = value
+				field.HasDefaultValue = value != ""
+			}
+			if field.DataType == schema.String && field.Size == 0 && indexed[field.DBName] {
+				field.Size = d.indexedStringSize
+			}
+		}
+	}
+	return nil
+}
+
+// configurePool adjusts the connection pool to limits of the database
+func (d *Dialect) configurePool(sqlDB *sql.DB, cfg *DatabaseConfig) {
+	if d.Name == DBTypeSQLite && cfg.DBName == sqliteMemory {
+		// Every connection to an in-memory database opens a new, empty one,
+		// so the whole app shares a single connection that is never recycled
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+	}
+}
+
+// schemaAdapter is a GORM plugin applying Dialect.AdaptSchema to the
+// database it is registered with, for tools that open their own connection.
+type schemaAdapter struct {
+	dialect *Dialect
+	models  []any
+}
+
+func (a schemaAdapter) Name() string { return "core:schema_adapter" }
+
+func (a schemaAdapter) Initialize(db *gorm.DB) error {
+	return a.dialect.AdaptSchema(db, a.models...)
+}
+
+// openPostgres opens a pgx pool whose connections send a cancel request when
+// the context of a query ends, so the server stops the query instead of only
+// the client giving up on it
+func openPostgres(dsn string) (*sql.DB, error) {
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgres dsn: %w", err)
+	}
+	config.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{
+			Conn: conn,
+			// Give up on the connection if the server does not answer the cancel
+			DeadlineDelay: 5 * time.Second,
+		}
+	}
+	return stdlib.OpenDB(*config), nil
+}
+
+// isolatePostgresSchema puts the schema name first on the search path of tx,
+// keeping the current schema next for the extensions installed there
+func isolatePostgresSchema(tx *gorm.DB, name string) error {
+	if err := tx.Exec(fmt.Sprintf("CREATE SCHEMA %q", name)).Error; err != nil {
+		return err
+	}
+	return tx.Exec("SELECT set_config('search_path', ? || ', ' || current_schema(), true)", name).Error
+}
+
+// postgresDualWrite derives the new column in a trigger function, on inserts
+// and on updates changing the old column
+func postgresDualWrite(change ColumnChange, quote func(string) string) (create, drop []string) {
+	function := quote(change.triggerName(""))
+	table := quote(change.Table)
+	create = []string{
+		fmt.Sprintf(`CREATE FUNCTION %s() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' OR NEW.%s IS DISTINCT FROM OLD.%s THEN
+    NEW.%s := %s;
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;`, function, quote(change.From), quote(change.From), quote(change.To), change.derive("NEW."+quote(change.From))),
+		fmt.Sprintf("CREATE TRIGGER %s BEFORE INSERT OR UPDATE ON %s FOR EACH ROW EXECUTE FUNCTION %s();", function, table, function),
+	}
+	drop = []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;", function, table),
+		fmt.Sprintf("DROP FUNCTION IF EXISTS %s();", function),
+	}
+	return create, drop
+}
+
+// mysqlDualWrite derives the new column in a trigger per write, as MySQL
+// triggers fire on a single event
+func mysqlDualWrite(change ColumnChange, quote func(string) string) (create, drop []string) {
+	for _, event := range []string{"INSERT", "UPDATE"} {
+		trigger := quote(change.triggerName(strings.ToLower(event)))
+		create = append(create, fmt.Sprintf("CREATE TRIGGER %s BEFORE %s ON %s FOR EACH ROW SET NEW.%s = %s;",
+			trigger, event, quote(change.Table), quote(change.To), change.derive("NEW."+quote(change.From))))
+		drop = append(drop, fmt.Sprintf("DROP TRIGGER IF EXISTS %s;", trigger))
+	}
+	return create, drop
+}
+
+func sqlserverURL(cfg *DatabaseConfig) string {
+	query := url.Values{"database": {cfg.DBName}}
+	switch cfg.SSLMode {
+	case "disable":
+		query.Set("encrypt", "disable")
+	case "require":
+		query.Set("encrypt", "true")
+		query.Set("TrustServerCertificate", "true")
+	default:
+		query.Set("encrypt", "true")
+	}
+
+	u := url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+func sqliteDSN(cfg *DatabaseConfig) string {
+	if cfg.DBName == sqliteMemory {
+		return "file::memory:?_foreign_keys=1"
+	}
+	return fmt.Sprintf("file:%s?_foreign_keys=1&_busy_timeout=5000&_journal_mode=WAL", cfg.DBName)
+}
+
+// mysqlDSN formats the go-sql-driver DSN, which escapes the credentials and
+// database name
+func mysqlDSN(cfg *DatabaseConfig) string {
+	config := mysqldriver.NewConfig()
+	config.User = cfg.User
+	config.Passwd = cfg.Password
+	config.Net = "tcp"
+	config.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	config.DBName = cfg.DBName
+	config.ParseTime = true
+	config.Loc = time.UTC
+	config.TLSConfig = mysqlTLS(cfg.SSLMode)
+	config.Params = map[string]string{"charset": "utf8mb4"}
+	return config.FormatDSN()
+}
+
+// mysqlTLS maps a Postgres style sslmode to the go-sql-driver tls parameter
+func mysqlTLS(sslMode string) string {
+	switch sslMode {
+	case "disable":
+		return "false"
+	case "require":
+		return "skip-verify"
+	default:
+		return "true"
+	}
+}
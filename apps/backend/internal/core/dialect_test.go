@@ -0,0 +1,40 @@
+package core
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestMySQLDSNEscapesCredentials(t *testing.T) {
+	cfg := &DatabaseConfig{
+		User:     "app@ops",
+		Password: "p@ss:/w?rd&tls=false",
+		Host:     "db.internal",
+		Port:     3306,
+		DBName:   "app",
+		SSLMode:  "require",
+	}
+
+	parsed, err := mysqldriver.ParseDSN(mysqlDialect.dsn(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.User != cfg.User || parsed.Passwd != cfg.Password || parsed.DBName != cfg.DBName {
+		t.Errorf("credentials = %q:%q@%q, want %q:%q@%q",
+			parsed.User, parsed.Passwd, parsed.DBName, cfg.User, cfg.Password, cfg.DBName)
+	}
+	if parsed.Addr != "db.internal:3306" || parsed.TLSConfig != "skip-verify" || !parsed.ParseTime || parsed.Loc != time.UTC {
+		t.Errorf("parsed dsn = %+v", parsed)
+	}
+
+	u, err := url.Parse(mysqlDialect.url(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, _ := u.User.Password(); u.User.Username() != cfg.User || password != cfg.Password {
+		t.Errorf("url credentials = %s, want %q:%q", u.User, cfg.User, cfg.Password)
+	}
+}
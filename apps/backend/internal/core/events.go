@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/fx"
+)
+
+// Event is a message published on the EventBus
+type Event interface {
+	Topic() string
+}
+
+// EventHandler reacts to a published event
+type EventHandler func(ctx context.Context, event Event) error
+
+// DeliveryMode controls when and where a subscriber runs
+type DeliveryMode int
+
+const (
+	// DeliverSync runs the handler after commit, in the publisher's goroutine
+	DeliverSync DeliveryMode = iota
+	// DeliverAsync runs the handler after commit, in its own goroutine
+	DeliverAsync
+	// DeliverInTx runs the handler immediately inside the publisher's
+	// transaction. An error aborts the publish and rolls the transaction back.
+	DeliverInTx
+)
+
+type SubscribeOption func(*subscription)
+
+// WithDelivery sets the delivery mode of a subscriber (DeliverSync by default)
+func WithDelivery(mode DeliveryMode) SubscribeOption {
+	return func(s *subscription) { s.mode = mode }
+}
+
+// EventBus is an in-process publish/subscribe bus that lets modules react to
+// each other's changes without depending on each other.
+type EventBus interface {
+	// Publish delivers event to every subscriber whose pattern matches its topic
+	Publish(ctx context.Context, event Event) error
+
+	// Subscribe registers handler for a topic. Patterns ending in ".*" match
+	// every topic with that prefix and "*" matches all topics.
+	Subscribe(pattern string, handler EventHandler, opts ...SubscribeOption)
+
+	// HasSubscribers reports whether any subscriber matches topic
+	HasSubscribers(topic string) bool
+}
+
+type subscription struct {
+	pattern string
+	handler EventHandler
+	mode    DeliveryMode
+}
+
+func (s subscription) matches(topic string) bool {
+	return TopicMatches(s.pattern, topic)
+}
+
+// TopicMatches reports whether a subscription pattern matches topic
+func TopicMatches(pattern, topic string) bool {
+	if pattern == "*" || pattern == topic {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(topic, prefix)
+	}
+	return false
+}
+
+type inProcessEventBus struct {
+	mu   sync.RWMutex
+	subs []subscription
+	log  Logger
+	wg   sync.WaitGroup
+}
+
+func NewEventBus(lc fx.Lifecycle, log Logger) EventBus {
+	bus := &inProcessEventBus{log: log}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return bus.wait(ctx)
+		},
+	})
+
+	return bus
+}
+
+func (b *inProcessEventBus) Subscribe(pattern string, handler EventHandler, opts ...SubscribeOption) {
+	sub := subscription{pattern: pattern, handler: handler, mode: DeliverSync}
+	for _, opt := range opts {
+		opt(&sub)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, sub)
+}
+
+func (b *inProcessEventBus) HasSubscribers(topic string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subs {
+		if sub.matches(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *inProcessEventBus) Publish(ctx context.Context, event Event) error {
+	topic := event.Topic()
+
+	var inTx, afterCommit []subscription
+	b.mu.RLock()
+	for _, sub := range b.subs {
+		if !sub.matches(topic) {
+			continue
+		}
+		if sub.mode == DeliverInTx {
+			inTx = append(inTx, sub)
+		} else {
+			afterCommit = append(afterCommit, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range inTx {
+		if err := sub.handler(ctx, event); err != nil {
+			return fmt.Errorf("event handler for %s failed: %w", topic, err)
+		}
+	}
+
+	if len(afterCommit) == 0 {
+		return nil
+	}
+
+	AfterCommit(ctx, func(ctx context.Context) {
+		for _, sub := range afterCommit {
+			if sub.mode == DeliverAsync {
+				b.dispatchAsync(ctx, sub, event)
+				continue
+			}
+			b.dispatch(ctx, sub, event)
+		}
+	})
+	return nil
+}
+
+func (b *inProcessEventBus) dispatch(ctx context.Context, sub subscription, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.log.Error("Event handler panicked",
+				String("topic", event.Topic()),
+				Any("panic", r))
+		}
+	}()
+
+	if err := sub.handler(ctx, event); err != nil {
+		b.log.Error("Event handler failed",
+			String("topic", event.Topic()),
+			String("pattern", sub.pattern),
+			Error(err))
+	}
+}
+
+func (b *inProcessEventBus) dispatchAsync(ctx context.Context, sub subscription, event Event) {
+	// Async handlers outlive the request that published the event
+	ctx = context.WithoutCancel(ctx)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.dispatch(ctx, sub, event)
+	}()
+}
+
+// wait blocks until in-flight async handlers finish or ctx expires
+func (b *inProcessEventBus) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -75,10 +76,11 @@ func (l *zapLogger) With(fields ...zap.Field) Logger {
 	}
 }
 
-// WithContext creates a logger with context
+// WithContext creates a logger tagged with the request ID carried by ctx
 func (l *zapLogger) WithContext(ctx context.Context) Logger {
-	// Extract fields from context if needed
-	// For now, just return the same logger
+	if id, ok := RequestIDFromContext(ctx); ok {
+		return l.With(String("request_id", id))
+	}
 	return l
 }
 
@@ -106,9 +108,10 @@ func parseLevel(level string) zapcore.Level {
 }
 
 // Convenience functions for zap fields
-func String(key, val string) zap.Field      { return zap.String(key, val) }
-func Int(key string, val int) zap.Field     { return zap.Int(key, val) }
-func Int64(key string, val int64) zap.Field { return zap.Int64(key, val) }
-func Bool(key string, val bool) zap.Field   { return zap.Bool(key, val) }
-func Error(err error) zap.Field             { return zap.Error(err) }
-func Any(key string, val any) zap.Field     { return zap.Any(key, val) }
+func String(key, val string) zap.Field                 { return zap.String(key, val) }
+func Int(key string, val int) zap.Field                { return zap.Int(key, val) }
+func Int64(key string, val int64) zap.Field            { return zap.Int64(key, val) }
+func Bool(key string, val bool) zap.Field              { return zap.Bool(key, val) }
+func Error(err error) zap.Field                        { return zap.Error(err) }
+func Any(key string, val any) zap.Field                { return zap.Any(key, val) }
+func Duration(key string, val time.Duration) zap.Field { return zap.Duration(key, val) }
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "core:query_start"
+
+// NewMetricsRegistry creates the registry served on the metrics endpoint
+func NewMetricsRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// queryMetrics is a GORM plugin recording the latency and errors of queries
+// per table and operation
+type queryMetrics struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+func newQueryMetrics(registry prometheus.Registerer) (*queryMetrics, error) {
+	m := &queryMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Duration of database queries by table and operation.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"table", "operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_query_errors_total",
+			Help: "Failed database queries by table and operation.",
+		}, []string{"table", "operation"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.duration, m.errors} {
+		if err := registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *queryMetrics) Name() string { return "core:query_metrics" }
+
+func (m *queryMetrics) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("core:metrics_before_create", m.before),
+		cb.Create().After("*").Register("core:metrics_after_create", m.after("create")),
+		cb.Query().Before("*").Register("core:metrics_before_query", m.before),
+		cb.Query().After("*").Register("core:metrics_after_query", m.after("query")),
+		cb.Update().Before("*").Register("core:metrics_before_update", m.before),
+		cb.Update().After("*").Register("core:metrics_after_update", m.after("update")),
+		cb.Delete().Before("*").Register("core:metrics_before_delete", m.before),
+		cb.Delete().After("*").Register("core:metrics_after_delete", m.after("delete")),
+		cb.Row().Before("*").Register("core:metrics_before_row", m.before),
+		cb.Row().After("*").Register("core:metrics_after_row", m.after("row")),
+		cb.Raw().Before("*").Register("core:metrics_before_raw", m.before),
+		cb.Raw().After("*").Register("core:metrics_after_raw", m.after("raw")),
+	)
+}
+
+func (m *queryMetrics) before(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (m *queryMetrics) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		m.duration.WithLabelValues(table, operation).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			m.errors.WithLabelValues(table, operation).Inc()
+		}
+	}
+}
+
+// registerPoolMetrics exposes the connection pool stats of the primary and
+// every replica
+func registerPoolMetrics(registry prometheus.Registerer, cfg *Config, db *gorm.DB, replicas []replica) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := registry.Register(collectors.NewDBStatsCollector(sqlDB, cfg.Database.DBName)); err != nil {
+		return err
+	}
+	for _, r := range replicas {
+		if err := registry.Register(collectors.NewDBStatsCollector(r.db, r.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
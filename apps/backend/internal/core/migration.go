@@ -1,17 +1,24 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
+
+	"ariga.io/atlas/sql/migrate"
 )
 
 type Migrator struct {
-	schema *SchemaManager
-	config *Config
-	log    Logger
-	db     *Database
+	schema  *SchemaManager
+	config  *Config
+	log     Logger
+	db      *Database
+	seeders []Seeder
 }
 
 func NewMigrator(
@@ -19,12 +26,14 @@ func NewMigrator(
 	config *Config,
 	logger Logger,
 	db *Database,
+	seeders []Seeder,
 ) *Migrator {
 	return &Migrator{
-		schema: schema,
-		config: config,
-		log:    logger,
-		db:     db,
+		schema:  schema,
+		config:  config,
+		log:     logger,
+		db:      db,
+		seeders: seeders,
 	}
 }
 
@@ -45,35 +54,46 @@ func (m *Migrator) ListModules() {
 	m.log.Info("entities", Int("total_entities", totalEntities))
 }
 
+// migrationTarget is a migrations directory to generate and the entities
+// whose DDL it holds
+type migrationTarget struct {
+	module   string
+	dir      string
+	entities []any
+}
+
+// GenerateMigration diffs the registered entities against their migrations
+// directories into new migrations. It is the only command that needs the
+// Atlas CLI; applying and checking migrations run in process.
 func (m *Migrator) GenerateMigration(migrationName, moduleFilter string) error {
 	env := m.config.App.Environment
 
-	var entities []interface{}
-	var targetModules string
-
-	if moduleFilter == "" {
-		entities = m.schema.GetAllEntities()
-		targetModules = "ALL"
-		m.log.Info("Generating migration for all module",
-			Int("total_entities", len(entities)))
-	} else {
-		mods := strings.Split(moduleFilter, ",")
-		for i, mod := range mods {
-			mods[i] = strings.TrimSpace(mod)
+	var modules []string
+	targetModules := "ALL"
+	if moduleFilter != "" {
+		modules = strings.Split(moduleFilter, ",")
+		for i, mod := range modules {
+			modules[i] = strings.TrimSpace(mod)
 		}
-		entities = m.schema.GetEntitiesByModules(mods...)
-		targetModules = strings.Join(mods, ",")
-		m.log.Info("Generating migration for specific modules",
-			Int("total_entities", len(entities)))
+		targetModules = strings.Join(modules, ",")
+	}
+
+	targets, err := m.migrationTargets(modules)
+	if err != nil {
+		return err
+	}
+	entities := 0
+	for _, target := range targets {
+		entities += len(target.entities)
 	}
 
 	m.log.Info("Generating Migration")
 	m.log.Info("Name: ", String("name", migrationName))
 	m.log.Info("Modules: ", String("modules", targetModules))
-	m.log.Info("Entities: ", Int("entities", len(entities)))
+	m.log.Info("Entities: ", Int("entities", entities))
 	m.log.Info("Env: ", String("env", env))
 
-	// Schema file
+	// Schema file, the snapshot of every entity verify compares with
 	schemaFile := "schema/schema.sql"
 
 	file, err := os.Create(schemaFile)
@@ -83,7 +103,7 @@ func (m *Migrator) GenerateMigration(migrationName, moduleFilter string) error {
 	}
 
 	// Load gorm entities
-	err = m.schema.LoadGORMSchema(file, m.config, m.db)
+	err = m.schema.LoadGORMSchema(file, m.snapshotConfig(), m.schema.GetAllEntities())
 	if err != nil {
 		m.log.Fatal("Failed to load gorm schema", Error(err))
 		return err
@@ -97,84 +117,434 @@ func (m *Migrator) GenerateMigration(migrationName, moduleFilter string) error {
 	m.log.Info("Schema file created", String("schema_file", schemaFile))
 	m.log.Info("Migration name", String("migration_name", migrationName))
 
+	dialect, err := m.config.Dialect()
+	if err != nil {
+		return err
+	}
+	if dialect.autoMigrate {
+		return fmt.Errorf("%s schemas are created with AutoMigrate, generate migrations against a server database", dialect.Name)
+	}
+
+	for _, target := range targets {
+		if err := m.checkMigrationsDialect(target.module); err != nil {
+			return err
+		}
+	}
+	for _, target := range targets {
+		if err := m.generateMigration(dialect, migrationName, target); err != nil {
+			m.log.Fatal("Migration generation failed", String("module", moduleLabel(target.module)), Error(err))
+			return err
+		}
+	}
+
+	// Docs are regenerated with the migrations, so reviewers see the model changes
+	if err := m.WriteSchemaDocs(); err != nil {
+		return err
+	}
+
+	m.log.Info("Migration generated successfully", String("migrationName", migrationName))
+	fmt.Println("\n Migration generated successfully!")
+
+	return nil
+}
+
+// migrationTargets lists the directories holding the migrations of modules,
+// every directory when modules is empty. The shared directory is diffed
+// against the entities of all the modules sharing it, so naming one of them
+// also picks up the changes of the others.
+func (m *Migrator) migrationTargets(modules []string) ([]migrationTarget, error) {
+	shared := migrationTarget{module: sharedModule, dir: m.migrationsPath()}
+	sharedWanted := len(modules) == 0
+	var owned []migrationTarget
+	registered := make(map[string]bool)
+
+	for _, provider := range m.schema.Providers() {
+		name := provider.ModuleName()
+		registered[name] = true
+		wanted := len(modules) == 0 || slices.Contains(modules, name)
+
+		if migrations, ok := provider.(MigrationProvider); ok {
+			if wanted {
+				owned = append(owned, migrationTarget{
+					module:   name,
+					dir:      migrations.MigrationsDir(),
+					entities: provider.Entities(),
+				})
+			}
+			continue
+		}
+		shared.entities = append(shared.entities, provider.Entities()...)
+		sharedWanted = sharedWanted || wanted
+	}
+
+	for _, name := range modules {
+		if !registered[name] {
+			return nil, fmt.Errorf("module %s is not registered", name)
+		}
+	}
+	if !sharedWanted || len(shared.entities) == 0 {
+		return owned, nil
+	}
+	return append([]migrationTarget{shared}, owned...), nil
+}
+
+// generateMigration diffs the entities of target against its directory into
+// a new migration, and writes its down script
+func (m *Migrator) generateMigration(dialect *Dialect, migrationName string, target migrationTarget) error {
+	desired, err := os.CreateTemp("", "schema-*.sql")
+	if err != nil {
+		return fmt.Errorf("failed to create schema file: %w", err)
+	}
+	defer os.Remove(desired.Name())
+
+	err = m.schema.LoadGORMSchema(desired, m.config, target.entities)
+	if closeErr := desired.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	previous, err := migrationFiles(target.dir)
+	if err != nil {
+		return err
+	}
+
 	// nolint:gosec // G204: Arguments are derived from validated application configuration, not untrusted user input.
 	cmd := exec.Command("atlas", "migrate", "diff",
 		migrationName,
-		"--to", fmt.Sprintf("file://%s", schemaFile),
-		"--dir", "file://migrations",
-		"--dev-url", "docker://postgres?search_path=public",
+		"--to", "file://"+desired.Name(),
+		"--dir", "file://"+target.dir,
+		"--dev-url", dialect.devURL,
 	)
 
 	m.log.Debug("Atlas command", String("command", cmd.String()))
 
 	m.log.Info("Running Atlas migration generation",
+		String("module", moduleLabel(target.module)),
+		String("dir", target.dir),
 		String("migration_name", migrationName),
-		String("environment", env))
+		String("environment", m.config.App.Environment))
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		fmt.Println(string(output))
-		m.log.Fatal("Migration generation failed", Error(err))
 		return err
 	}
 
-	m.log.Info("Migration generated successfully", String("migrationName", migrationName))
-	fmt.Println("\n Migration generated successfully!")
+	return m.writeDownMigration(dialect, target.dir, previous)
+}
+
+// writeDownMigration writes the down script of the migration generated after
+// previous, the files dirPath held before, by diffing the directory back to
+// the latest of them. When there is nothing to diff back to or Atlas fails,
+// a script without statements is left to be written by hand.
+func (m *Migrator) writeDownMigration(dialect *Dialect, dirPath string, previous []migrate.File) error {
+	files, err := migrationFiles(dirPath)
+	if err != nil {
+		return err
+	}
+	if len(files) == len(previous) {
+		// Atlas found no changes and wrote no migration
+		return nil
+	}
+	generated := files[len(files)-1]
+
+	down := fmt.Sprintf("-- Reverts %s. Rollbacks refuse to run until it has statements.\n", generated.Name())
+	if len(previous) > 0 {
+		// nolint:gosec // G204: Arguments are derived from validated application configuration, not untrusted user input.
+		cmd := exec.Command("atlas", "schema", "diff",
+			"--from", "file://"+dirPath,
+			"--to", fmt.Sprintf("file://%s?version=%s", dirPath, previous[len(previous)-1].Version()),
+			"--dev-url", dialect.devURL,
+		)
+		m.log.Debug("Atlas command", String("command", cmd.String()))
+
+		output, err := cmd.Output()
+		if err == nil && !strings.Contains(string(output), "Schemas are synced") {
+			down = string(output)
+		} else {
+			m.log.Warn("Could not generate the down migration, write it by hand", Error(err))
+		}
+	}
 
+	downPath := filepath.Join(dirPath, downMigrationsDir, generated.Name())
+	if err := os.MkdirAll(filepath.Dir(downPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create down migrations directory: %w", err)
+	}
+	if err := os.WriteFile(downPath, []byte(down), 0o600); err != nil {
+		return fmt.Errorf("failed to write down migration: %w", err)
+	}
+	m.log.Info("Down migration written, review it before deploying", String("path", downPath))
 	return nil
 }
 
-func (m *Migrator) CheckStatus() {
-	m.log.Info("Migration Status")
-	m.log.Info("Environment: %s", String("env", m.config.App.Environment))
+// migrationFiles lists the migrations of dirPath in version order, none when
+// the directory does not exist yet
+func migrationFiles(dirPath string) ([]migrate.File, error) {
+	if !dirExists(dirPath) {
+		return nil, nil
+	}
+	dir, err := migrate.NewLocalDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations directory: %w", err)
+	}
+	return dir.Files()
+}
 
-	// nolint:gosec // G204: Arguments are derived from validated application configuration, not untrusted user input.
-	cmd := exec.Command("atlas", "migrate", "status",
-		"--dir", "file://migrations",
-		"--env", m.config.App.Environment,
-		"--dev-url", "docker://postgres:latest",
-		"--to", m.config.GetDatabaseUrl(),
-	)
+// CheckStatus prints the applied and pending migrations
+func (m *Migrator) CheckStatus() error {
+	m.log.Info("Migration Status", String("env", m.config.App.Environment))
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if m.db.Dialect().autoMigrate {
+		m.log.Info("Schema is managed by AutoMigrate, there are no migrations to check",
+			String("type", m.db.Dialect().Name))
+		return nil
+	}
 
-	if err := cmd.Run(); err != nil {
+	status, err := m.Status(context.Background())
+	if err != nil {
 		m.log.Error("Failed to check migration status", Error(err))
-		m.log.Fatal("Status check failed")
+		return err
+	}
+
+	current := status.Current
+	if current == "" {
+		current = "none"
+	}
+	fmt.Printf("\n Current version: %s\n", current)
+	fmt.Printf(" Applied: %d, pending: %d\n\n", len(status.Applied), len(status.Pending))
+	for _, rev := range status.Applied {
+		fmt.Printf("  [applied] %-10s %s %-35s %s\n",
+			moduleLabel(rev.Module), rev.Version, rev.Description, rev.AppliedAt.Format(time.RFC3339))
+	}
+	for _, mig := range status.Pending {
+		fmt.Printf("  [pending] %-10s %s %s\n", moduleLabel(mig.Module), mig.Version, mig.Description)
+	}
+
+	changes, err := m.ColumnChanges(context.Background())
+	if err != nil {
+		m.log.Error("Failed to check column changes", Error(err))
+		return err
+	}
+	if len(changes) > 0 {
+		fmt.Printf("\n Column changes:\n")
 	}
+	for _, change := range changes {
+		phase := change.Phase
+		switch phase {
+		case ChangePlanned:
+			phase += ", run expand"
+		case ChangeExpanded:
+			phase += ", run backfill"
+		case ChangeBackfilling:
+			phase = fmt.Sprintf("%s, %d rows so far, run backfill to resume", phase, change.Rows)
+		case ChangeBackfilled:
+			phase += ", run contract once the app uses the new column"
+		}
+		fmt.Printf("  %-10s %-25s %s.%s -> %s: %s\n",
+			moduleLabel(change.Module), change.Name, change.Table, change.From, change.To, phase)
+	}
+	return nil
 }
 
-func (m *Migrator) ApplyMigrations() error {
-	m.log.Info("Starting migration process...")
-	m.log.Info("Migration directory: file://migrations")
-	m.log.Info("Target database", String("url", m.config.GetDatabaseUrl()))
+// ApplyMigrations applies the pending migrations of every migrations directory,
+// or runs AutoMigrate for dialects without migrations
+func (m *Migrator) ApplyMigrations(ctx context.Context) error {
+	if m.db.Dialect().autoMigrate {
+		return m.AutoMigrate(ctx)
+	}
 
-	// nolint:gosec // G204: Arguments are derived from validated application configuration, not untrusted user input.
-	cmd := exec.Command("atlas", "migrate", "apply",
-		"--dir", "file://migrations",
-		"--url", m.config.GetDatabaseUrl(),
-	)
+	source := "embedded"
+	if m.config.Database.MigrationsDir != "" {
+		source = m.config.Database.MigrationsDir
+	}
+	m.log.Info("Starting migration process...", String("migrations", source))
 
-	output, err := cmd.CombinedOutput()
-	outputStr := string(output)
-
-	// Log each line of Atlas output to see migration details
-	if outputStr != "" {
-		m.log.Info("Atlas migration output:")
-		for _, line := range strings.Split(strings.TrimSpace(outputStr), "\n") {
-			if line != "" {
-				m.log.Info("  " + line)
+	status, err := m.migrate(ctx)
+	if err != nil {
+		m.log.Error("Migration failed", Error(err))
+		return err
+	}
+
+	m.log.Info("Migrations applied successfully!",
+		String("version", status.Current),
+		Int("applied", len(status.Applied)))
+	return nil
+}
+
+// PlanMigrations prints the SQL the pending migrations would run, and returns
+// an error when the linter flags statements that may lose data or block writes
+func (m *Migrator) PlanMigrations() error {
+	if m.db.Dialect().autoMigrate {
+		m.log.Info("Schema is managed by AutoMigrate, there are no migrations to plan",
+			String("type", m.db.Dialect().Name))
+		return nil
+	}
+
+	plan, err := m.Plan(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(plan.Migrations) == 0 {
+		fmt.Println("\n No pending migrations")
+		return nil
+	}
+
+	for _, mig := range plan.Migrations {
+		fmt.Printf("\n-- %s %s %s\n", moduleLabel(mig.Module), mig.Version, mig.Description)
+		for _, stmt := range mig.Statements {
+			fmt.Println(stmt)
+		}
+	}
+	if len(plan.Findings) == 0 {
+		fmt.Printf("\n %d pending migrations, no unsafe statements\n", len(plan.Migrations))
+		return nil
+	}
+
+	fmt.Printf("\n Unsafe statements:\n")
+	for _, f := range plan.Findings {
+		fmt.Printf("  %s statement %d [%s] %s\n    %s\n", modulePath(f.Module, f.File), f.Statement, f.Rule, f.Message, f.SQL)
+	}
+	fmt.Println("\n Allow a statement with a `-- atlas:nolint <rule>` comment above it")
+	return fmt.Errorf("%d unsafe statements in pending migrations", len(plan.Findings))
+}
+
+// DetectDrift prints how the migrations differ from the entities and how the
+// live database differs from its applied migrations, and returns an error
+// when they differ
+func (m *Migrator) DetectDrift() error {
+	if m.db.Dialect().autoMigrate {
+		m.log.Info("Schema is managed by AutoMigrate, there are no migrations to drift from",
+			String("type", m.db.Dialect().Name))
+		return nil
+	}
+
+	report, err := m.Drift(context.Background())
+	if err != nil {
+		return err
+	}
+
+	printDrift := func(title string, drift []SchemaDrift) {
+		if len(drift) == 0 {
+			return
+		}
+		fmt.Printf("\n %s:\n", title)
+		for _, d := range drift {
+			change := "extra  "
+			if d.Missing {
+				change = "missing"
 			}
+			fmt.Printf("  %s %-6s %s\n", change, d.Kind, d.Name)
 		}
 	}
+	printDrift("Migrations differ from the entities, run generate", report.Migrations)
+	printDrift("Live database differs from its applied migrations", report.Live)
+
+	if drift := len(report.Migrations) + len(report.Live); drift > 0 {
+		return fmt.Errorf("schema drift: %d differences", drift)
+	}
+	fmt.Println("\n Entities, migrations and database match")
+	return nil
+}
+
+// RevertMigrations reverts the latest steps applied migrations with their
+// down scripts
+func (m *Migrator) RevertMigrations(steps int) error {
+	if m.db.Dialect().autoMigrate {
+		return fmt.Errorf("%s schemas are managed by AutoMigrate and cannot be reverted", m.db.Dialect().Name)
+	}
+
+	m.log.Info("Reverting migrations", Int("steps", steps))
+	status, err := m.Down(context.Background(), steps)
+	if err != nil {
+		m.log.Error("Revert failed", Error(err))
+		return err
+	}
+
+	m.log.Info("Migrations reverted successfully!", String("version", status.Current))
+	return nil
+}
+
+// MigrateTo applies or reverts migrations until version is the latest
+// applied one; BaseVersion reverts every migration
+func (m *Migrator) MigrateTo(version string) error {
+	if m.db.Dialect().autoMigrate {
+		return fmt.Errorf("%s schemas are managed by AutoMigrate and have no versions", m.db.Dialect().Name)
+	}
 
+	m.log.Info("Migrating to version", String("version", version))
+	status, err := m.Goto(context.Background(), version)
 	if err != nil {
 		m.log.Error("Migration failed", Error(err))
-		return fmt.Errorf("atlas failed: %w, output: %s", err, outputStr)
+		return err
+	}
+
+	m.log.Info("Migrated to version successfully!", String("version", status.Current))
+	return nil
+}
+
+// VerifyMigrations prints whether the database matches the migrations and
+// returns an error when an applied migration was edited, removed or skipped
+func (m *Migrator) VerifyMigrations() error {
+	if m.db.Dialect().autoMigrate {
+		m.log.Info("Schema is managed by AutoMigrate, there are no migrations to verify",
+			String("type", m.db.Dialect().Name))
+		return nil
+	}
+
+	report, err := m.Verify(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n Applied: %d, pending: %d\n", len(report.Applied), len(report.Pending))
+	if report.SnapshotStale {
+		fmt.Println(" schema/schema.sql is out of date with the entities, run generate")
+	}
+	if len(report.Issues) == 0 {
+		fmt.Println(" Checksums of applied migrations match")
+		return nil
+	}
+	for _, issue := range report.Issues {
+		fmt.Printf("  %v\n", issue)
+	}
+	return fmt.Errorf("%d applied migrations do not match the migrations", len(report.Issues))
+}
+
+// snapshotConfig is the configuration schema/schema.sql is generated with. The
+// snapshot is always Postgres DDL, the dialect entity tags are written in, so
+// it does not change with the database it is checked against.
+func (m *Migrator) snapshotConfig() *Config {
+	cfg := *m.config
+	cfg.Database.Type = DBTypePostgres
+	return &cfg
+}
+
+// migrationsPath is the on-disk directory the migrations of the modules
+// without their own are generated into
+func (m *Migrator) migrationsPath() string {
+	if m.config.Database.MigrationsDir != "" {
+		return m.config.Database.MigrationsDir
+	}
+	return defaultMigrationsDir
+}
+
+// AutoMigrate creates or updates the tables of every registered entity with
+// GORM AutoMigrate, for databases the migrations directory does not target.
+func (m *Migrator) AutoMigrate(ctx context.Context) error {
+	entities := m.schema.GetAllEntities()
+	m.log.Info("Migrating schema with AutoMigrate", Int("entities", len(entities)))
+
+	if err := m.db.Dialect().AdaptSchema(m.db.DB, entities...); err != nil {
+		return err
+	}
+	if err := m.db.WithContext(ctx).AutoMigrate(entities...); err != nil {
+		m.log.Error("Migration failed", Error(err))
+		return fmt.Errorf("auto migration failed: %w", err)
 	}
 
-	m.log.Info("Migrations applied successfully!")
+	m.log.Info("Schema migrated successfully!")
 	return nil
 }
 
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"ariga.io/atlas/sql/migrate"
+	"gorm.io/gorm"
+)
+
+// Lint rules, named in `-- atlas:nolint <rule>...` comments to allow a
+// statement, or a whole file when the comment heads it. A bare
+// `-- atlas:nolint` allows every rule.
+const (
+	lintDropTable     = "drop_table"
+	lintDropColumn    = "drop_column"
+	lintAlterType     = "alter_type"
+	lintNotNull       = "not_null_without_default"
+	lintBlockingIndex = "blocking_index"
+)
+
+const nolintDirective = "nolint"
+
+var (
+	reDropTable  = regexp.MustCompile(`(?i)^\s*DROP\s+TABLE\b`)
+	reDropColumn = regexp.MustCompile(`(?i)\bDROP\s+COLUMN\b`)
+	reAlterType  = regexp.MustCompile(`(?i)^\s*ALTER\s+TYPE\b|\bALTER\s+COLUMN\s+\S+\s+(SET\s+DATA\s+)?TYPE\b|\b(MODIFY|CHANGE)\s+COLUMN\b`)
+	reAddColumn  = regexp.MustCompile(`(?i)^\s*(?:ALTER\s+TABLE\s+\S+\s+)?ADD\s+(?:COLUMN\s+)?(\S+)`)
+	reNotNull    = regexp.MustCompile(`(?i)\bNOT\s+NULL\b`)
+	reSetNotNull = regexp.MustCompile(`(?i)\bSET\s+NOT\s+NULL\b`)
+	// Columns filled in by the database on their own
+	reHasDefault   = regexp.MustCompile(`(?i)\b(DEFAULT|GENERATED|IDENTITY|AUTO_INCREMENT|\w*SERIAL)\b`)
+	reCreateTable  = regexp.MustCompile(`(?i)^\s*CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?((?:"[^"]+"|\w+)(?:\.(?:"[^"]+"|\w+))?)`)
+	reCreateIndex  = regexp.MustCompile(`(?i)^\s*CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?`)
+	reIndexedTable = regexp.MustCompile(`(?i)\bON\s+(?:ONLY\s+)?((?:"[^"]+"|\w+)(?:\.(?:"[^"]+"|\w+))?)`)
+)
+
+// addClauseKeywords start ADD clauses that add no column
+var addClauseKeywords = []string{"CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "INDEX", "KEY"}
+
+// LintFinding is a statement of a pending migration that may lose data or
+// block writes while it runs
+type LintFinding struct {
+	// Module owns the migration, empty for the shared migrations directory
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	File    string `json:"file"`
+	// Statement is the 1-based position of the statement in File
+	Statement int    `json:"statement"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+	SQL       string `json:"sql"`
+}
+
+// migrationLinter flags destructive and locking statements of migrations
+type migrationLinter struct {
+	dialect        *Dialect
+	conn           *gorm.DB
+	largeTableRows int64
+	// created are the tables created by the migrations linted so far, which
+	// are empty when indexed
+	created map[string]bool
+}
+
+func newMigrationLinter(dialect *Dialect, conn *gorm.DB, largeTableRows int64) *migrationLinter {
+	return &migrationLinter{
+		dialect:        dialect,
+		conn:           conn,
+		largeTableRows: largeTableRows,
+		created:        make(map[string]bool),
+	}
+}
+
+// lint checks the statements of file, in the order they are applied
+func (l *migrationLinter) lint(ctx context.Context, file migrate.File) ([]LintFinding, error) {
+	stmts, err := file.StmtDecls()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse migration %s: %w", file.Name(), err)
+	}
+	var fileAllowed []string
+	if local, ok := file.(*migrate.LocalFile); ok {
+		fileAllowed = local.Directive(nolintDirective)
+	}
+
+	var findings []LintFinding
+	for i, stmt := range stmts {
+		allowed := append(slices.Clone(fileAllowed), stmt.Directive(nolintDirective)...)
+		check := func(rule, message string) {
+			if lintAllowed(allowed, rule) {
+				return
+			}
+			findings = append(findings, LintFinding{
+				Version:   file.Version(),
+				File:      file.Name(),
+				Statement: i + 1,
+				Rule:      rule,
+				Message:   message,
+				SQL:       stmt.Text,
+			})
+		}
+
+		text := stmt.Text
+		if m := reCreateTable.FindStringSubmatch(text); m != nil {
+			l.created[normalizeTableName(m[1])] = true
+			continue
+		}
+		if reDropTable.MatchString(text) {
+			check(lintDropTable, "drops a table and its data")
+		}
+		if reDropColumn.MatchString(text) {
+			check(lintDropColumn, "drops a column and its data, and fails running code still reading it")
+		}
+		if reAlterType.MatchString(text) {
+			check(lintAlterType, "changes a column type, which may rewrite the table under an exclusive lock")
+		}
+		if addsNotNullWithoutDefault(text) {
+			check(lintNotNull, "adds NOT NULL without a default, which fails on existing rows or scans the table under an exclusive lock")
+		}
+
+		blocking, err := l.blockingIndex(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lint migration %s: %w", file.Name(), err)
+		}
+		if blocking {
+			check(lintBlockingIndex, "builds an index on a large table without CONCURRENTLY, blocking writes until it is done; "+
+				"CONCURRENTLY needs `-- atlas:txmode none`")
+		}
+	}
+	return findings, nil
+}
+
+// addsNotNullWithoutDefault reports whether stmt adds a NOT NULL column
+// without a default, or sets NOT NULL on an existing column
+func addsNotNullWithoutDefault(stmt string) bool {
+	if reSetNotNull.MatchString(stmt) {
+		return true
+	}
+	for _, clause := range splitClauses(stmt) {
+		m := reAddColumn.FindStringSubmatch(clause)
+		if m == nil || slices.Contains(addClauseKeywords, strings.ToUpper(m[1])) {
+			continue
+		}
+		if reNotNull.MatchString(clause) && !reHasDefault.MatchString(clause) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockingIndex reports whether stmt builds an index without CONCURRENTLY on
+// a table holding at least largeTableRows rows. Tables that were never
+// analyzed count as large.
+func (l *migrationLinter) blockingIndex(ctx context.Context, stmt string) (bool, error) {
+	if l.dialect.tableRowsQuery == "" {
+		return false, nil
+	}
+	m := reCreateIndex.FindStringSubmatch(stmt)
+	if m == nil || m[1] != "" {
+		return false, nil
+	}
+	table := reIndexedTable.FindStringSubmatch(stmt)
+	if table == nil || l.created[normalizeTableName(table[1])] {
+		return false, nil
+	}
+
+	var rows int64
+	err := l.conn.WithContext(ctx).Raw(l.dialect.tableRowsQuery, table[1]).Row().Scan(&rows)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The table is created by another pending migration
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to estimate rows of %s: %w", table[1], err)
+	}
+	return rows < 0 || rows >= l.largeTableRows, nil
+}
+
+// lintAllowed reports whether the nolint directives allowed allow rule
+func lintAllowed(allowed []string, rule string) bool {
+	for _, d := range allowed {
+		rules := strings.FieldsFunc(d, func(r rune) bool { return r == ' ' || r == ',' })
+		if len(rules) == 0 || slices.Contains(rules, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitClauses splits stmt at the commas outside parentheses and quotes
+func splitClauses(stmt string) []string {
+	var (
+		clauses []string
+		depth   int
+		quote   rune
+		start   int
+	)
+	for i, r := range stmt {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			clauses = append(clauses, stmt[start:i])
+			start = i + 1
+		}
+	}
+	return append(clauses, stmt[start:])
+}
+
+func normalizeTableName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, `"`, ""))
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAddsNotNullWithoutDefault(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want bool
+	}{
+		{`ALTER TABLE "users" ADD COLUMN "age" bigint NOT NULL`, true},
+		{`ALTER TABLE "users" ADD COLUMN "age" bigint NOT NULL DEFAULT 0`, false},
+		{`ALTER TABLE "users" ADD COLUMN "age" bigint NULL`, false},
+		{`ALTER TABLE users ADD age bigint NOT NULL`, true},
+		{`ALTER TABLE "users" ADD COLUMN "id" bigserial NOT NULL`, false},
+		{`ALTER TABLE "users" ADD COLUMN "n" bigint NOT NULL GENERATED ALWAYS AS IDENTITY`, false},
+		{`ALTER TABLE "users" ALTER COLUMN "email" SET NOT NULL`, true},
+		{`ALTER TABLE "users" ADD COLUMN "a" text NULL, ADD COLUMN "b" text NOT NULL`, true},
+		{`ALTER TABLE "users" ADD COLUMN "a" text NOT NULL DEFAULT 'x', ADD COLUMN "b" text NULL`, false},
+		{`ALTER TABLE "users" ADD CONSTRAINT "chk" CHECK ("age" IS NOT NULL)`, false},
+		{`ALTER TABLE "users" ADD PRIMARY KEY ("id")`, false},
+		{`CREATE TABLE "users" ("id" uuid NOT NULL)`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			if got := addsNotNullWithoutDefault(tt.stmt); got != tt.want {
+				t.Fatalf("addsNotNullWithoutDefault() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitClauses(t *testing.T) {
+	tests := []struct {
+		name string
+		stmt string
+		want []string
+	}{
+		{"single", `ADD COLUMN "a" text`, []string{`ADD COLUMN "a" text`}},
+		{"top level commas", `ADD "a" text, ADD "b" text`, []string{`ADD "a" text`, ` ADD "b" text`}},
+		{"parentheses", `ADD "a" numeric(10,2), ADD "b" text`, []string{`ADD "a" numeric(10,2)`, ` ADD "b" text`}},
+		{"single quotes", `ADD "a" text DEFAULT 'x,y'`, []string{`ADD "a" text DEFAULT 'x,y'`}},
+		{"double quotes", `ADD "a,b" text`, []string{`ADD "a,b" text`}},
+		{"backticks", "ADD `a,b` text, ADD c text", []string{"ADD `a,b` text", " ADD c text"}},
+		{"empty", ``, []string{``}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitClauses(tt.stmt); !slices.Equal(got, tt.want) {
+				t.Fatalf("splitClauses() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		rule    string
+		want    bool
+	}{
+		{"no directives", nil, lintDropTable, false},
+		{"bare directive", []string{""}, lintDropTable, true},
+		{"named rule", []string{lintDropTable}, lintDropTable, true},
+		{"other rule", []string{lintDropColumn}, lintDropTable, false},
+		{"space separated", []string{lintDropColumn + " " + lintDropTable}, lintDropTable, true},
+		{"comma separated", []string{lintDropColumn + "," + lintDropTable}, lintDropTable, true},
+		{"file and statement", []string{lintAlterType, lintNotNull}, lintNotNull, true},
+		{"prefix only", []string{"drop"}, lintDropTable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lintAllowed(tt.allowed, tt.rule); got != tt.want {
+				t.Fatalf("lintAllowed(%q, %q) = %v, want %v", tt.allowed, tt.rule, got, tt.want)
+			}
+		})
+	}
+}
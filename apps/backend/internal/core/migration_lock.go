@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// migrationLockName names the lock held while migrating, shared by every
+	// instance of the app migrating the same database
+	migrationLockName         = "go-next-flutter:migrations"
+	migrationLockPollInterval = time.Second
+)
+
+var (
+	// ErrMigrationLockTimeout is returned when another instance held the
+	// migration lock for longer than database.migration_lock_timeout
+	ErrMigrationLockTimeout = errors.New("timed out waiting for the migration lock")
+	// ErrSchemaBehind is returned at startup when migrations are pending and
+	// the app does not apply them itself
+	ErrSchemaBehind = errors.New("database schema is behind the migrations")
+)
+
+// withMigrationLock runs fn while holding the migration lock, so instances
+// starting together migrate the database one at a time. The lock belongs to a
+// dedicated connection and is released when fn returns or the connection drops.
+func (m *Migrator) withMigrationLock(ctx context.Context, fn func() error) error {
+	dialect := m.db.Dialect()
+	if dialect.lock.try == "" {
+		return fn()
+	}
+
+	sqlDB, err := m.db.DB.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open migration lock connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := m.acquireMigrationLock(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), dialect.lock.unlock, migrationLockName); err != nil {
+			m.log.Warn("Failed to release migration lock", Error(err))
+		}
+	}()
+	return fn()
+}
+
+// acquireMigrationLock takes the migration lock on conn, polling while another
+// instance holds it for up to database.migration_lock_timeout
+func (m *Migrator) acquireMigrationLock(ctx context.Context, conn *sql.Conn) error {
+	timeout := m.config.Database.MigrationLockTimeout
+	deadline := time.Now().Add(timeout)
+
+	for waited := false; ; waited = true {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, m.db.Dialect().lock.try, migrationLockName).Scan(&locked); err != nil {
+			return fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if locked {
+			if waited {
+				m.log.Info("Acquired migration lock")
+			}
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w after %s", ErrMigrationLockTimeout, timeout)
+		}
+		if !waited {
+			m.log.Info("Waiting for another instance to finish migrating", Duration("timeout", timeout))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(migrationLockPollInterval, remaining)):
+		}
+	}
+}
+
+// EnsureCurrent fails with ErrSchemaBehind when migrations are pending, for
+// instances that leave applying them to a separate job. It does not change
+// the database.
+func (m *Migrator) EnsureCurrent(ctx context.Context) error {
+	if m.db.Dialect().autoMigrate {
+		return nil
+	}
+
+	sets, err := m.loadMigrationSets()
+	if err != nil {
+		return err
+	}
+	applied, err := m.appliedRevisions(ctx)
+	if err != nil {
+		return err
+	}
+	status, issues := compareMigrationSets(sets, applied)
+	if len(issues) > 0 {
+		return errors.Join(issues...)
+	}
+	if len(status.Pending) > 0 {
+		latest := status.Pending[len(status.Pending)-1].path()
+		return fmt.Errorf("%w: %d pending up to %s, apply them with `cmd/schema -action=apply`",
+			ErrSchemaBehind, len(status.Pending), latest)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ariga.io/atlas/sql/migrate"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newLockTestMigrator returns a migrator of an in-memory SQLite database
+// shared by its connections, whose dialect emulates named session locks with
+// rows of test_locks: a lock is taken by the try that inserts its row
+func newLockTestMigrator(t *testing.T, lockTimeout time.Duration) *Migrator {
+	t.Helper()
+	dsn := "file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared"
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := conn.Exec("CREATE TABLE test_locks (name text PRIMARY KEY, tries integer)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	dialect := *sqliteDialect
+	dialect.lock.try = `INSERT INTO test_locks (name, tries) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET tries = tries + 1 RETURNING tries = 1`
+	dialect.lock.unlock = "DELETE FROM test_locks WHERE name = ?"
+
+	return &Migrator{
+		config: &Config{Database: DatabaseConfig{MigrationLockTimeout: lockTimeout}},
+		log:    &zapLogger{logger: zap.NewNop()},
+		db:     &Database{DB: conn, dialect: &dialect},
+	}
+}
+
+func lockHeld(t *testing.T, m *Migrator) bool {
+	t.Helper()
+	var held int64
+	if err := m.db.Table("test_locks").Where("name = ?", migrationLockName).Count(&held).Error; err != nil {
+		t.Fatal(err)
+	}
+	return held > 0
+}
+
+func TestWithMigrationLockReleasesLock(t *testing.T) {
+	m := newLockTestMigrator(t, time.Second)
+	errFn := errors.New("migration failed")
+
+	for _, want := range []error{nil, errFn} {
+		ran := false
+		err := m.withMigrationLock(context.Background(), func() error {
+			ran = true
+			if !lockHeld(t, m) {
+				t.Error("lock not held while migrating")
+			}
+			return want
+		})
+		if !ran || !errors.Is(err, want) {
+			t.Fatalf("withMigrationLock() = %v (ran %v), want %v", err, ran, want)
+		}
+		if lockHeld(t, m) {
+			t.Fatal("lock still held after migrating")
+		}
+	}
+}
+
+func TestWithMigrationLockWithoutLockQuery(t *testing.T) {
+	m := &Migrator{db: &Database{dialect: sqliteDialect}}
+	ran := false
+	if err := m.withMigrationLock(context.Background(), func() error { ran = true; return nil }); err != nil || !ran {
+		t.Fatalf("withMigrationLock() = %v (ran %v), want fn run without locking", err, ran)
+	}
+}
+
+func TestAcquireMigrationLockTimesOut(t *testing.T) {
+	m := newLockTestMigrator(t, 1500*time.Millisecond)
+	// Another instance holds the lock
+	if err := m.db.Exec("INSERT INTO test_locks (name, tries) VALUES (?, 1)", migrationLockName).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	err := m.withMigrationLock(context.Background(), func() error {
+		t.Error("migrated without the lock")
+		return nil
+	})
+	if !errors.Is(err, ErrMigrationLockTimeout) {
+		t.Fatalf("withMigrationLock() = %v, want %v", err, ErrMigrationLockTimeout)
+	}
+	if waited := time.Since(start); waited < 1500*time.Millisecond {
+		t.Fatalf("gave up after %s, want the lock timeout", waited)
+	}
+
+	var tries int
+	if err := m.db.Table("test_locks").Select("tries").Where("name = ?", migrationLockName).Scan(&tries).Error; err != nil {
+		t.Fatal(err)
+	}
+	// The holder's row counts as the first try: this instance tries once
+	// right away and again on each poll until the timeout
+	if attempts := tries - 1; attempts < 2 {
+		t.Fatalf("lock tried %d times, want it polled", attempts)
+	}
+}
+
+func TestAcquireMigrationLockWaitsForRelease(t *testing.T) {
+	m := newLockTestMigrator(t, 10*time.Second)
+	if err := m.db.Exec("INSERT INTO test_locks (name, tries) VALUES (?, 1)", migrationLockName).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// The other instance finishes migrating while this one waits
+	released := make(chan error, 1)
+	time.AfterFunc(200*time.Millisecond, func() {
+		released <- m.db.Exec("DELETE FROM test_locks WHERE name = ?", migrationLockName).Error
+	})
+
+	ran := false
+	if err := m.withMigrationLock(context.Background(), func() error { ran = true; return nil }); err != nil || !ran {
+		t.Fatalf("withMigrationLock() = %v (ran %v), want fn run once the lock is released", err, ran)
+	}
+	if err := <-released; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireMigrationLockHonoursContext(t *testing.T) {
+	m := newLockTestMigrator(t, 10*time.Second)
+	if err := m.db.Exec("INSERT INTO test_locks (name, tries) VALUES (?, 1)", migrationLockName).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := m.withMigrationLock(ctx, func() error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("withMigrationLock() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// writeTestMigrations writes files to a migrations directory with its atlas.sum
+func writeTestMigrations(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(path, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, err := migrate.NewLocalDir(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := dir.Checksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate.WriteSumFile(dir, sum); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEnsureCurrent(t *testing.T) {
+	m := newSQLiteMigrator(t)
+	m.config.Database.MigrationsDir = writeTestMigrations(t, map[string]string{
+		"1_first.sql":  "CREATE TABLE first (id integer);",
+		"2_second.sql": "CREATE TABLE second (id integer);",
+	})
+	// Databases created with AutoMigrate have no migrations to check
+	if err := m.EnsureCurrent(context.Background()); err != nil {
+		t.Fatalf("EnsureCurrent() = %v on an AutoMigrate database", err)
+	}
+	dialect := *sqliteDialect
+	dialect.autoMigrate = false
+	m.db.dialect = &dialect
+
+	migrations, err := m.loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyTestMigrations(t, m, migrations[:1])
+	if err := m.EnsureCurrent(context.Background()); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("EnsureCurrent() = %v with a pending migration, want %v", err, ErrSchemaBehind)
+	}
+
+	applyTestMigrations(t, m, migrations[1:])
+	if err := m.EnsureCurrent(context.Background()); err != nil {
+		t.Fatalf("EnsureCurrent() = %v with every migration applied", err)
+	}
+	if !m.db.Migrator().HasTable("second") {
+		t.Fatal("migrations were not applied")
+	}
+}
+
+func TestApplyMigrationsHonoursContext(t *testing.T) {
+	m := newSQLiteMigrator(t)
+	m.config.Database.MigrationsDir = writeTestMigrations(t, map[string]string{
+		"1_first.sql": "CREATE TABLE first (id integer);",
+	})
+	dialect := *sqliteDialect
+	dialect.autoMigrate = false
+	m.db.dialect = &dialect
+
+	// Startup gave up, e.g. on the fx start timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.ApplyMigrations(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ApplyMigrations() = %v, want %v", err, context.Canceled)
+	}
+	if m.db.Migrator().HasTable("first") {
+		t.Fatal("migration applied after the context ended")
+	}
+
+	if err := m.ApplyMigrations(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !m.db.Migrator().HasTable("first") {
+		t.Fatal("migration not applied")
+	}
+}
@@ -0,0 +1,877 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"ariga.io/atlas/sql/migrate"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+
+	"github.com/johna210/go-next-flutter/migrations"
+	"github.com/johna210/go-next-flutter/schema"
+)
+
+const (
+	// defaultMigrationsDir is where migrations are generated when
+	// database.migrations_dir is not set
+	defaultMigrationsDir = "migrations"
+	// downMigrationsDir is the subdirectory of the migrations directory holding
+	// the down scripts, named after the migrations they revert
+	downMigrationsDir = "down"
+	// atlasRevisionsTable is where the Atlas CLI recorded applied migrations,
+	// adopted by the runner the first time it migrates such a database
+	atlasRevisionsTable = "atlas_schema_revisions"
+	// BaseVersion is the target version that reverts every migration
+	BaseVersion = "0"
+	// sharedModule is the module of the migrations directory shared by the
+	// modules that do not own one
+	sharedModule = ""
+)
+
+var (
+	// ErrMigrationChanged is returned when an applied migration file was edited
+	ErrMigrationChanged = errors.New("applied migration was modified")
+	// ErrMigrationMissing is returned when an applied migration file was removed
+	ErrMigrationMissing = errors.New("applied migration is missing from the migrations directory")
+	// ErrMigrationOutOfOrder is returned when a pending migration is older
+	// than the latest applied one
+	ErrMigrationOutOfOrder = errors.New("pending migration is older than the latest applied one")
+	// ErrDownMigrationMissing is returned when a migration to revert has no
+	// down script, or one without statements
+	ErrDownMigrationMissing = errors.New("down migration is missing")
+	// ErrUnknownMigrationVersion is returned when a target version matches no migration
+	ErrUnknownMigrationVersion = errors.New("no migration has the version")
+	// ErrMigrationDependencyCycle is returned when modules owning migrations
+	// depend on each other
+	ErrMigrationDependencyCycle = errors.New("module migrations depend on each other")
+	// ErrMigrationsUnsupported is returned when the migrations to apply are
+	// written for another database than the configured one
+	ErrMigrationsUnsupported = errors.New("migrations are not written for this database")
+)
+
+// SchemaRevision records a migration applied to the database
+type SchemaRevision struct {
+	// Module owns the migration, empty for the shared migrations directory
+	Module      string    `gorm:"size:64;primaryKey;default:''" json:"module"`
+	Version     string    `gorm:"size:64;primaryKey"            json:"version"`
+	Description string    `gorm:"size:255;not null"             json:"description"`
+	Checksum    string    `gorm:"size:64;not null"              json:"checksum"`
+	AppliedAt   time.Time `gorm:"not null"                      json:"applied_at"`
+	ExecutionMs int64     `gorm:"not null;default:0"            json:"execution_ms"`
+}
+
+func (SchemaRevision) TableName() string { return "schema_revisions" }
+
+// Migration is a versioned SQL file of a migrations directory
+type Migration struct {
+	// Module owns the migration, empty for the shared migrations directory
+	Module      string `json:"module"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	// Checksum is the hash atlas.sum records for the file
+	Checksum string `json:"checksum"`
+
+	file migrate.File
+	// down reverts file, nil when no down script was written
+	down migrate.File
+}
+
+// MigrationStatus compares the migrations directories with the database
+type MigrationStatus struct {
+	// Current is the latest version applied to any module, empty for a new
+	// database
+	Current string           `json:"current"`
+	Applied []SchemaRevision `json:"applied"`
+	// Pending are in the order they are applied
+	Pending []Migration `json:"pending"`
+}
+
+// migrationSet is the migrations of a directory, applied after the sets of
+// the modules it depends on
+type migrationSet struct {
+	// module owns the directory, sharedModule for the migrations directory
+	module     string
+	dependsOn  []string
+	migrations []Migration
+}
+
+// MigrationPlan lists what applying the pending migrations would run
+type MigrationPlan struct {
+	Migrations []PlannedMigration `json:"migrations"`
+	// Findings are the statements that may lose data or block writes
+	Findings []LintFinding `json:"findings"`
+}
+
+// PlannedMigration is a pending migration and the statements it runs
+type PlannedMigration struct {
+	Migration
+	Statements []string `json:"statements"`
+}
+
+// MigrationReport is the outcome of verifying the database against the migrations
+type MigrationReport struct {
+	MigrationStatus
+	// Issues are the applied revisions that were edited, removed or skipped
+	Issues []error `json:"-"`
+	// SnapshotStale is set when schema.sql no longer matches the entities
+	SnapshotStale bool `json:"snapshot_stale"`
+}
+
+// loadMigrations reads the migrations of dir in version order after
+// verifying them against atlas.sum, pairing each with its script in down
+func loadMigrations(module string, dir, down migrate.Dir) ([]Migration, error) {
+	if err := migrate.Validate(dir); err != nil {
+		return nil, fmt.Errorf("migrations of %s do not match atlas.sum, run `atlas migrate hash` after editing them: %w",
+			moduleLabel(module), err)
+	}
+	files, err := dir.Files()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	sum, err := dir.Checksum()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash migrations: %w", err)
+	}
+
+	downFiles, err := down.Files()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read down migrations: %w", err)
+	}
+	downByVersion := make(map[string]migrate.File, len(downFiles))
+	for _, file := range downFiles {
+		downByVersion[file.Version()] = file
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		checksum, err := sum.SumByName(file.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to find checksum of %s: %w", file.Name(), err)
+		}
+		migrations = append(migrations, Migration{
+			Module:      module,
+			Version:     file.Version(),
+			Description: file.Desc(),
+			Checksum:    checksum,
+			file:        file,
+			down:        downByVersion[file.Version()],
+		})
+	}
+	return migrations, nil
+}
+
+// migrationConn returns a connection for running migrations. Their DDL
+// bypasses the prepared statement cache and the default statement timeout.
+func (m *Migrator) migrationConn(ctx context.Context) *gorm.DB {
+	conn := m.db.DB.Session(&gorm.Session{NewDB: true, Context: WithStatementTimeout(ctx, 0)}).
+		Clauses(dbresolver.Write)
+	if prepared, ok := conn.Statement.ConnPool.(*gorm.PreparedStmtDB); ok {
+		conn.Statement.ConnPool = prepared.ConnPool
+	}
+	return conn.Session(&gorm.Session{})
+}
+
+// Status reports which migrations are applied and which are pending. It
+// fails when an applied migration was edited, removed or skipped.
+func (m *Migrator) Status(ctx context.Context) (*MigrationStatus, error) {
+	status, _, err := m.status(ctx)
+	return status, err
+}
+
+// status is Status also returning the migration sets it was compared with
+func (m *Migrator) status(ctx context.Context) (*MigrationStatus, []migrationSet, error) {
+	sets, err := m.loadMigrationSets()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.ensureRevisionsTable(ctx); err != nil {
+		return nil, nil, err
+	}
+	applied, err := m.readRevisions(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, issues := compareMigrationSets(sets, applied)
+	if len(issues) > 0 {
+		return nil, nil, errors.Join(issues...)
+	}
+	return status, sets, nil
+}
+
+// Verify compares the checksums of the migrations with those recorded for the
+// revisions applied to the database, without changing the database
+func (m *Migrator) Verify(ctx context.Context) (*MigrationReport, error) {
+	sets, err := m.loadMigrationSets()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedRevisions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stale, err := m.snapshotStale()
+	if err != nil {
+		return nil, err
+	}
+
+	status, issues := compareMigrationSets(sets, applied)
+	return &MigrationReport{MigrationStatus: *status, Issues: issues, SnapshotStale: stale}, nil
+}
+
+// snapshotStale reports whether the embedded schema.sql differs from the DDL
+// of the registered entities, ignoring the order of the statements. Both are
+// Postgres DDL whatever the configured database.
+func (m *Migrator) snapshotStale() (bool, error) {
+	var current bytes.Buffer
+	if err := m.schema.LoadGORMSchema(&current, m.snapshotConfig(), m.schema.GetAllEntities()); err != nil {
+		return false, err
+	}
+
+	want := strings.Split(strings.TrimSpace(current.String()), "\n")
+	got := strings.Split(strings.TrimSpace(schema.Snapshot), "\n")
+	slices.Sort(want)
+	slices.Sort(got)
+	return !slices.Equal(want, got), nil
+}
+
+// Plan lists the statements of the pending migrations and lints them, without
+// changing the database
+func (m *Migrator) Plan(ctx context.Context) (*MigrationPlan, error) {
+	sets, err := m.loadMigrationSets()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedRevisions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, issues := compareMigrationSets(sets, applied)
+	if len(issues) > 0 {
+		return nil, errors.Join(issues...)
+	}
+
+	plan := &MigrationPlan{}
+	linter := newMigrationLinter(m.db.Dialect(), m.migrationConn(ctx), m.config.Database.LintLargeTableRows)
+	for _, mig := range status.Pending {
+		stmts, err := mig.file.Stmts()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse migration %s: %w", mig.path(), err)
+		}
+		findings, err := linter.lint(ctx, mig.file)
+		if err != nil {
+			return nil, err
+		}
+		for i := range findings {
+			findings[i].Module = mig.Module
+		}
+		plan.Migrations = append(plan.Migrations, PlannedMigration{Migration: mig, Statements: stmts})
+		plan.Findings = append(plan.Findings, findings...)
+	}
+	return plan, nil
+}
+
+// appliedRevisions reads the applied revisions, none when the revisions table
+// was not created yet
+func (m *Migrator) appliedRevisions(ctx context.Context) ([]SchemaRevision, error) {
+	if !m.migrationConn(ctx).Migrator().HasTable(&SchemaRevision{}) {
+		return nil, nil
+	}
+	return m.readRevisions(ctx)
+}
+
+func (m *Migrator) readRevisions(ctx context.Context) ([]SchemaRevision, error) {
+	var applied []SchemaRevision
+	if err := m.migrationConn(ctx).Order("version").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema revisions: %w", err)
+	}
+	return applied, nil
+}
+
+// compareMigrationSets compares every set with the revisions of its module,
+// listing the pending migrations in the order they are applied
+func compareMigrationSets(sets []migrationSet, applied []SchemaRevision) (*MigrationStatus, []error) {
+	byModule := make(map[string][]SchemaRevision)
+	for _, rev := range applied {
+		byModule[rev.Module] = append(byModule[rev.Module], rev)
+	}
+
+	status := &MigrationStatus{}
+	var issues []error
+	for _, set := range sets {
+		setStatus, setIssues := compareRevisions(set.migrations, byModule[set.module])
+		delete(byModule, set.module)
+
+		status.Current = max(status.Current, setStatus.Current)
+		status.Applied = append(status.Applied, setStatus.Applied...)
+		status.Pending = append(status.Pending, setStatus.Pending...)
+		issues = append(issues, setIssues...)
+	}
+	// Revisions of modules that are no longer registered
+	for _, module := range slices.Sorted(maps.Keys(byModule)) {
+		for _, rev := range byModule[module] {
+			issues = append(issues, fmt.Errorf("%w: %s", ErrMigrationMissing, rev.path()))
+		}
+	}
+	return status, issues
+}
+
+// compareRevisions finds the pending migrations of a set and the applied
+// revisions that were edited, removed or skipped since they were applied
+func compareRevisions(migrations []Migration, applied []SchemaRevision) (*MigrationStatus, []error) {
+	status := &MigrationStatus{Applied: applied}
+	byVersion := make(map[string]SchemaRevision, len(applied))
+	for _, rev := range applied {
+		byVersion[rev.Version] = rev
+		status.Current = rev.Version
+	}
+
+	var issues []error
+	known := make(map[string]bool, len(migrations))
+	for _, mig := range migrations {
+		known[mig.Version] = true
+		rev, ok := byVersion[mig.Version]
+		switch {
+		case !ok && mig.Version < status.Current:
+			issues = append(issues, fmt.Errorf("%w: %s is older than %s", ErrMigrationOutOfOrder, mig.path(), status.Current))
+		case !ok:
+			status.Pending = append(status.Pending, mig)
+		case rev.Checksum != mig.Checksum:
+			issues = append(issues, fmt.Errorf("%w: %s", ErrMigrationChanged, mig.path()))
+		}
+	}
+	for _, rev := range applied {
+		if !known[rev.Version] {
+			issues = append(issues, fmt.Errorf("%w: %s", ErrMigrationMissing, rev.path()))
+		}
+	}
+	return status, issues
+}
+
+// loadMigrationSets reads the shared migrations directory and those of the
+// modules owning one, ordered so every set follows the sets it depends on
+func (m *Migrator) loadMigrationSets() ([]migrationSet, error) {
+	shared, err := m.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	sets := map[string]*migrationSet{
+		sharedModule: {module: sharedModule, migrations: shared},
+	}
+
+	// owners maps every module to the module of the set holding its migrations
+	providers := m.schema.Providers()
+	owners := make(map[string]string, len(providers))
+	for _, provider := range providers {
+		name := provider.ModuleName()
+		owners[name] = sharedModule
+
+		owned, ok := provider.(MigrationProvider)
+		if !ok {
+			continue
+		}
+		if err := m.checkMigrationsDialect(name); err != nil {
+			return nil, err
+		}
+		migrations, err := loadModuleMigrations(name, owned.Migrations())
+		if err != nil {
+			return nil, err
+		}
+		owners[name] = name
+		sets[name] = &migrationSet{module: name, migrations: migrations}
+	}
+
+	for _, provider := range providers {
+		dependent, ok := provider.(DependentProvider)
+		if !ok {
+			continue
+		}
+		set := sets[owners[provider.ModuleName()]]
+		for _, dep := range dependent.DependsOn() {
+			owner, ok := owners[dep]
+			if !ok {
+				return nil, fmt.Errorf("module %s depends on unregistered module %s", provider.ModuleName(), dep)
+			}
+			if owner != set.module && !slices.Contains(set.dependsOn, owner) {
+				set.dependsOn = append(set.dependsOn, owner)
+			}
+		}
+	}
+	return orderMigrationSets(sets)
+}
+
+// orderMigrationSets orders sets after the sets they depend on, and by module
+// otherwise, the shared directory first
+func orderMigrationSets(sets map[string]*migrationSet) ([]migrationSet, error) {
+	ordered := make([]migrationSet, 0, len(sets))
+	done := make(map[string]bool, len(sets))
+	for len(ordered) < len(sets) {
+		progressed := false
+		for _, name := range slices.Sorted(maps.Keys(sets)) {
+			set := sets[name]
+			if done[name] || slices.ContainsFunc(set.dependsOn, func(dep string) bool { return !done[dep] }) {
+				continue
+			}
+			ordered = append(ordered, *set)
+			done[name] = true
+			progressed = true
+		}
+		if !progressed {
+			var cycle []string
+			for _, name := range slices.Sorted(maps.Keys(sets)) {
+				if !done[name] {
+					cycle = append(cycle, moduleLabel(name))
+				}
+			}
+			return nil, fmt.Errorf("%w: %s", ErrMigrationDependencyCycle, strings.Join(cycle, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+// relatedSets returns the modules of the sets module depends on, directly or
+// through other sets, or of the sets depending on it when dependents is set
+func relatedSets(sets []migrationSet, module string, dependents bool) map[string]bool {
+	edges := make(map[string][]string)
+	for _, set := range sets {
+		for _, dep := range set.dependsOn {
+			if dependents {
+				edges[dep] = append(edges[dep], set.module)
+			} else {
+				edges[set.module] = append(edges[set.module], dep)
+			}
+		}
+	}
+
+	related := make(map[string]bool)
+	queue := slices.Clone(edges[module])
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if !related[next] {
+			related[next] = true
+			queue = append(queue, edges[next]...)
+		}
+	}
+	return related
+}
+
+// loadMigrations reads the directory set by database.migrations_dir, or the
+// migrations embedded in the binary
+func (m *Migrator) loadMigrations() ([]Migration, error) {
+	if path := m.config.Database.MigrationsDir; path != "" {
+		dir, err := migrate.NewLocalDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open migrations directory: %w", err)
+		}
+		var down migrate.Dir = &migrate.MemDir{}
+		if downPath := filepath.Join(path, downMigrationsDir); dirExists(downPath) {
+			if down, err = migrate.NewLocalDir(downPath); err != nil {
+				return nil, fmt.Errorf("failed to open down migrations directory: %w", err)
+			}
+		}
+		return loadMigrations(sharedModule, dir, down)
+	}
+	if err := m.checkMigrationsDialect(sharedModule); err != nil {
+		return nil, err
+	}
+	return loadModuleMigrations(sharedModule, migrations.FS)
+}
+
+// checkMigrationsDialect fails with ErrMigrationsUnsupported when the
+// migrations of module are embedded and written for another database than
+// the configured one. The shared migrations can be replaced through
+// database.migrations_dir, those of modules cannot.
+func (m *Migrator) checkMigrationsDialect(module string) error {
+	dialect := m.db.Dialect()
+	if dialect.embeddedMigrations {
+		return nil
+	}
+	if module == sharedModule {
+		if m.config.Database.MigrationsDir != "" {
+			return nil
+		}
+		return fmt.Errorf("%w: the embedded migrations are written for %s, set database.migrations_dir to migrations generated for %s",
+			ErrMigrationsUnsupported, DBTypePostgres, dialect.Name)
+	}
+	return fmt.Errorf("%w: module %s embeds migrations written for %s, not %s",
+		ErrMigrationsUnsupported, module, DBTypePostgres, dialect.Name)
+}
+
+// loadModuleMigrations reads the migrations of module from fsys, holding them
+// at its root and the down scripts in its down directory
+func loadModuleMigrations(module string, fsys fs.FS) ([]Migration, error) {
+	dir, err := embeddedDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	down, err := embeddedDir(fsys, downMigrationsDir)
+	if err != nil {
+		return nil, err
+	}
+	return loadMigrations(module, dir, down)
+}
+
+// embeddedDir copies the files of the embedded migrations directory name, none
+// when it does not exist
+func embeddedDir(fsys fs.FS, name string) (*migrate.MemDir, error) {
+	dir := &migrate.MemDir{}
+	entries, err := fs.ReadDir(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return dir, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(name, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+		}
+		if err := dir.WriteFile(entry.Name(), data); err != nil {
+			return nil, err
+		}
+	}
+	return dir, nil
+}
+
+// migrate applies every pending migration, the modules in dependency order,
+// each in its own transaction together with its revision record, while
+// holding the migration lock
+func (m *Migrator) migrate(ctx context.Context) (status *MigrationStatus, err error) {
+	err = m.withMigrationLock(ctx, func() error {
+		if status, err = m.Status(ctx); err != nil {
+			return err
+		}
+		if err := m.apply(ctx, status.Pending); err != nil {
+			return err
+		}
+		status, err = m.Status(ctx)
+		return err
+	})
+	return status, err
+}
+
+// apply applies pending in order
+func (m *Migrator) apply(ctx context.Context, pending []Migration) error {
+	for _, mig := range pending {
+		if err := m.checkContract(ctx, mig); err != nil {
+			return err
+		}
+		m.log.Info("Applying migration",
+			String("module", moduleLabel(mig.Module)),
+			String("version", mig.Version),
+			String("description", mig.Description))
+
+		rev, err := m.applyMigration(ctx, mig)
+		if err != nil {
+			return err
+		}
+
+		m.log.Info("Migration applied",
+			String("module", moduleLabel(rev.Module)),
+			String("version", rev.Version),
+			Int64("execution_ms", rev.ExecutionMs))
+	}
+	return nil
+}
+
+func (m *Migrator) applyMigration(ctx context.Context, mig Migration) (SchemaRevision, error) {
+	stmts, err := mig.file.Stmts()
+	if err != nil {
+		return SchemaRevision{}, fmt.Errorf("failed to parse migration %s: %w", mig.path(), err)
+	}
+
+	start := time.Now()
+	var rev SchemaRevision
+	err = m.runMigration(ctx, mig.file, func(conn *gorm.DB) error {
+		if err := execStatements(conn, mig, stmts); err != nil {
+			return err
+		}
+		rev = SchemaRevision{
+			Module:      mig.Module,
+			Version:     mig.Version,
+			Description: mig.Description,
+			Checksum:    mig.Checksum,
+			AppliedAt:   time.Now().UTC(),
+			ExecutionMs: time.Since(start).Milliseconds(),
+		}
+		if err := conn.Create(&rev).Error; err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", mig.path(), err)
+		}
+		return nil
+	})
+	return rev, err
+}
+
+// Down reverts the latest steps applied migrations, the most recently applied
+// first
+func (m *Migrator) Down(ctx context.Context, steps int) (status *MigrationStatus, err error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1, got %d", steps)
+	}
+	err = m.withMigrationLock(ctx, func() error {
+		var sets []migrationSet
+		if status, sets, err = m.status(ctx); err != nil {
+			return err
+		}
+		reverted := newestFirst(status.Applied)
+		if err := m.revert(ctx, sets, reverted[:min(steps, len(reverted))]); err != nil {
+			return err
+		}
+		status, err = m.Status(ctx)
+		return err
+	})
+	return status, err
+}
+
+// Goto migrates the module owning version to it, applying its pending
+// migrations up to it after those of the modules it depends on, or reverting
+// its migrations after it together with those the modules depending on it
+// applied since. BaseVersion reverts every migration of every module.
+func (m *Migrator) Goto(ctx context.Context, version string) (status *MigrationStatus, err error) {
+	err = m.withMigrationLock(ctx, func() error {
+		var sets []migrationSet
+		if status, sets, err = m.status(ctx); err != nil {
+			return err
+		}
+		if err := m.migrateTo(ctx, status, sets, version); err != nil {
+			return err
+		}
+		status, err = m.Status(ctx)
+		return err
+	})
+	return status, err
+}
+
+func (m *Migrator) migrateTo(ctx context.Context, status *MigrationStatus, sets []migrationSet, version string) error {
+	if version == BaseVersion {
+		return m.revert(ctx, sets, newestFirst(status.Applied))
+	}
+
+	var modules []string
+	for _, set := range sets {
+		if slices.ContainsFunc(set.migrations, func(mig Migration) bool { return mig.Version == version }) {
+			modules = append(modules, set.module)
+		}
+	}
+	switch len(modules) {
+	case 0:
+		return fmt.Errorf("%w: %s", ErrUnknownMigrationVersion, version)
+	case 1:
+	default:
+		labels := make([]string, len(modules))
+		for i, module := range modules {
+			labels[i] = moduleLabel(module)
+		}
+		return fmt.Errorf("version %s is ambiguous, the migrations of %s have it", version, strings.Join(labels, ", "))
+	}
+	module := modules[0]
+
+	if i := slices.IndexFunc(status.Applied, func(rev SchemaRevision) bool {
+		return rev.Module == module && rev.Version == version
+	}); i >= 0 {
+		target := status.Applied[i]
+		dependents := relatedSets(sets, module, true)
+		var reverted []SchemaRevision
+		for _, rev := range status.Applied {
+			if rev.Module == module && rev.Version > version ||
+				dependents[rev.Module] && rev.AppliedAt.After(target.AppliedAt) {
+				reverted = append(reverted, rev)
+			}
+		}
+		return m.revert(ctx, sets, newestFirst(reverted))
+	}
+
+	dependencies := relatedSets(sets, module, false)
+	var pending []Migration
+	for _, mig := range status.Pending {
+		if mig.Module == module && mig.Version <= version || dependencies[mig.Module] {
+			pending = append(pending, mig)
+		}
+	}
+	return m.apply(ctx, pending)
+}
+
+// newestFirst orders revs the most recently applied first
+func newestFirst(revs []SchemaRevision) []SchemaRevision {
+	return slices.SortedStableFunc(slices.Values(revs), func(a, b SchemaRevision) int {
+		if c := b.AppliedAt.Compare(a.AppliedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.Version, a.Version)
+	})
+}
+
+// revert runs the down scripts of the migrations of reverted in order. Nothing
+// is reverted unless every one of them has a down script.
+func (m *Migrator) revert(ctx context.Context, sets []migrationSet, reverted []SchemaRevision) error {
+	byRevision := make(map[string]Migration)
+	for _, set := range sets {
+		for _, mig := range set.migrations {
+			byRevision[modulePath(mig.Module, mig.Version)] = mig
+		}
+	}
+
+	stmts := make([][]string, len(reverted))
+	var missing []error
+	for i, rev := range reverted {
+		mig := byRevision[modulePath(rev.Module, rev.Version)]
+		if mig.down != nil {
+			var err error
+			if stmts[i], err = mig.down.Stmts(); err != nil {
+				return fmt.Errorf("failed to parse down migration %s: %w", mig.downPath(), err)
+			}
+		}
+		if len(stmts[i]) == 0 {
+			missing = append(missing, fmt.Errorf("%w: %s", ErrDownMigrationMissing, mig.downPath()))
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Join(missing...)
+	}
+
+	for i, rev := range reverted {
+		mig := byRevision[modulePath(rev.Module, rev.Version)]
+		m.log.Info("Reverting migration",
+			String("module", moduleLabel(mig.Module)),
+			String("version", mig.Version),
+			String("description", mig.Description))
+
+		start := time.Now()
+		err := m.runMigration(ctx, mig.down, func(conn *gorm.DB) error {
+			if err := execStatements(conn, mig, stmts[i]); err != nil {
+				return err
+			}
+			err := conn.Where("module = ? AND version = ?", mig.Module, mig.Version).Delete(&SchemaRevision{}).Error
+			if err != nil {
+				return fmt.Errorf("failed to remove revision of migration %s: %w", mig.path(), err)
+			}
+			return m.forgetBackfill(conn, mig)
+		})
+		if err != nil {
+			return err
+		}
+
+		m.log.Info("Migration reverted",
+			String("module", moduleLabel(mig.Module)),
+			String("version", mig.Version),
+			Int64("execution_ms", time.Since(start).Milliseconds()))
+	}
+	return nil
+}
+
+// runMigration runs fn in a transaction, unless the dialect cannot roll back
+// schema changes or file opts out with `-- atlas:txmode none`
+func (m *Migrator) runMigration(ctx context.Context, file migrate.File, fn func(conn *gorm.DB) error) error {
+	conn := m.migrationConn(ctx)
+	if !m.db.Dialect().transactionalDDL {
+		return fn(conn)
+	}
+	// Statements such as CREATE INDEX CONCURRENTLY cannot run in a transaction
+	if local, ok := file.(*migrate.LocalFile); ok && hasDirective(local, "txmode", "none") {
+		return fn(conn)
+	}
+	return conn.Transaction(fn)
+}
+
+func execStatements(conn *gorm.DB, mig Migration, stmts []string) error {
+	for i, stmt := range stmts {
+		if err := conn.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("migration %s failed at statement %d: %w", mig.path(), i+1, err)
+		}
+	}
+	return nil
+}
+
+// ensureRevisionsTable creates the revisions table, adopting the revisions
+// recorded by the Atlas CLI when the database was migrated with it before
+func (m *Migrator) ensureRevisionsTable(ctx context.Context) error {
+	conn := m.migrationConn(ctx)
+	if conn.Migrator().HasTable(&SchemaRevision{}) {
+		return nil
+	}
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&SchemaRevision{}); err != nil {
+			return fmt.Errorf("failed to create schema revisions table: %w", err)
+		}
+
+		for _, table := range []string{atlasRevisionsTable + "." + atlasRevisionsTable, atlasRevisionsTable} {
+			if !tx.Migrator().HasTable(table) {
+				continue
+			}
+			result := tx.Exec(fmt.Sprintf(
+				`INSERT INTO schema_revisions (version, description, checksum, applied_at, execution_ms)
+				SELECT version, description, hash, executed_at, execution_time / 1000000
+				FROM %s WHERE applied = total`, table))
+			if result.Error != nil {
+				return fmt.Errorf("failed to adopt atlas revisions: %w", result.Error)
+			}
+			m.log.Info("Adopted migrations applied by the Atlas CLI", Int64("revisions", result.RowsAffected))
+			break
+		}
+		return nil
+	})
+}
+
+// path names the migration file, prefixed with the module owning it
+func (mig Migration) path() string {
+	return modulePath(mig.Module, mig.file.Name())
+}
+
+// downPath names the down script of the migration, prefixed with the module
+// owning it
+func (mig Migration) downPath() string {
+	return modulePath(mig.Module, path.Join(downMigrationsDir, mig.file.Name()))
+}
+
+// path names the migration of the revision, prefixed with the module owning it
+func (rev SchemaRevision) path() string {
+	return modulePath(rev.Module, rev.Version+"_"+rev.Description)
+}
+
+// modulePath prefixes name with module, unless it is the shared directory
+func modulePath(module, name string) string {
+	if module == sharedModule {
+		return name
+	}
+	return module + "/" + name
+}
+
+// moduleLabel names module in logs and output
+func moduleLabel(module string) string {
+	if module == sharedModule {
+		return "shared"
+	}
+	return module
+}
+
+func dirExists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
+
+func hasDirective(file *migrate.LocalFile, name, value string) bool {
+	for _, d := range file.Directive(name) {
+		if d == value {
+			return true
+		}
+	}
+	return false
+}
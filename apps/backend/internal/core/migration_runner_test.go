@@ -0,0 +1,480 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"ariga.io/atlas/sql/migrate"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func testMigration(module, version, checksum string) Migration {
+	return Migration{
+		Module:      module,
+		Version:     version,
+		Description: "change",
+		Checksum:    checksum,
+		file:        migrate.NewLocalFile(version+"_change.sql", nil),
+	}
+}
+
+func testRevision(module, version, checksum string) SchemaRevision {
+	return SchemaRevision{Module: module, Version: version, Description: "change.sql", Checksum: checksum}
+}
+
+func versions(migrations []Migration) []string {
+	var got []string
+	for _, mig := range migrations {
+		got = append(got, mig.Module+"/"+mig.Version)
+	}
+	return got
+}
+
+func TestCompareRevisions(t *testing.T) {
+	tests := []struct {
+		name        string
+		migrations  []Migration
+		applied     []SchemaRevision
+		wantCurrent string
+		wantPending []string
+		wantIssues  []error
+	}{
+		{
+			name:        "new database",
+			migrations:  []Migration{testMigration("", "1", "a"), testMigration("", "2", "b")},
+			wantPending: []string{"/1", "/2"},
+		},
+		{
+			name:        "up to date",
+			migrations:  []Migration{testMigration("", "1", "a"), testMigration("", "2", "b")},
+			applied:     []SchemaRevision{testRevision("", "1", "a"), testRevision("", "2", "b")},
+			wantCurrent: "2",
+		},
+		{
+			name:        "pending after current",
+			migrations:  []Migration{testMigration("", "1", "a"), testMigration("", "2", "b")},
+			applied:     []SchemaRevision{testRevision("", "1", "a")},
+			wantCurrent: "1",
+			wantPending: []string{"/2"},
+		},
+		{
+			name:        "edited",
+			migrations:  []Migration{testMigration("", "1", "changed")},
+			applied:     []SchemaRevision{testRevision("", "1", "a")},
+			wantCurrent: "1",
+			wantIssues:  []error{ErrMigrationChanged},
+		},
+		{
+			name:        "removed",
+			migrations:  []Migration{testMigration("", "2", "b")},
+			applied:     []SchemaRevision{testRevision("", "1", "a"), testRevision("", "2", "b")},
+			wantCurrent: "2",
+			wantIssues:  []error{ErrMigrationMissing},
+		},
+		{
+			name:        "older than current",
+			migrations:  []Migration{testMigration("", "1", "a"), testMigration("", "2", "b"), testMigration("", "3", "c")},
+			applied:     []SchemaRevision{testRevision("", "2", "b")},
+			wantCurrent: "2",
+			wantPending: []string{"/3"},
+			wantIssues:  []error{ErrMigrationOutOfOrder},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, issues := compareRevisions(tt.migrations, tt.applied)
+			if status.Current != tt.wantCurrent {
+				t.Errorf("Current = %q, want %q", status.Current, tt.wantCurrent)
+			}
+			if got := versions(status.Pending); !slices.Equal(got, tt.wantPending) {
+				t.Errorf("Pending = %v, want %v", got, tt.wantPending)
+			}
+			if len(issues) != len(tt.wantIssues) {
+				t.Fatalf("issues = %v, want %v", issues, tt.wantIssues)
+			}
+			for i, want := range tt.wantIssues {
+				if !errors.Is(issues[i], want) {
+					t.Errorf("issues[%d] = %v, want %v", i, issues[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareMigrationSets(t *testing.T) {
+	sets := []migrationSet{
+		{module: sharedModule, migrations: []Migration{testMigration("", "1", "a"), testMigration("", "3", "c")}},
+		{module: "audit", migrations: []Migration{testMigration("audit", "2", "b"), testMigration("audit", "4", "d")}},
+	}
+	applied := []SchemaRevision{
+		testRevision("", "1", "a"),
+		testRevision("audit", "2", "b"),
+		testRevision("billing", "5", "e"),
+	}
+
+	status, issues := compareMigrationSets(sets, applied)
+	// Versions are compared per module, so the shared 3 is not older than audit's 2
+	if want := []string{"/3", "audit/4"}; !slices.Equal(versions(status.Pending), want) {
+		t.Errorf("Pending = %v, want %v", versions(status.Pending), want)
+	}
+	if status.Current != "2" {
+		t.Errorf("Current = %q, want 2", status.Current)
+	}
+	if len(status.Applied) != 2 {
+		t.Errorf("Applied = %v, want the revisions of registered modules", status.Applied)
+	}
+	// The revision of the unregistered module is reported missing
+	if len(issues) != 1 || !errors.Is(issues[0], ErrMigrationMissing) {
+		t.Fatalf("issues = %v, want one ErrMigrationMissing", issues)
+	}
+}
+
+// newSQLiteMigrator returns a migrator of an in-memory SQLite database with
+// its revisions table created
+func newSQLiteMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	conn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	m := &Migrator{
+		schema: NewSchemaManager(),
+		config: &Config{},
+		log:    &zapLogger{logger: zap.NewNop()},
+		db:     &Database{DB: conn, dialect: sqliteDialect},
+	}
+	if err := m.ensureRevisionsTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// applyTestMigrations runs the up scripts of migrations and records them
+func applyTestMigrations(t *testing.T, m *Migrator, migrations []Migration) *MigrationStatus {
+	t.Helper()
+	status := &MigrationStatus{}
+	for _, mig := range migrations {
+		rev, err := m.applyMigration(context.Background(), mig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status.Applied = append(status.Applied, rev)
+		status.Current = rev.Version
+	}
+	return status
+}
+
+func TestRevertRequiresDownScripts(t *testing.T) {
+	upDown := func(version, up, down string) Migration {
+		mig := testMigration("", version, version)
+		mig.file = migrate.NewLocalFile(version+"_change.sql", []byte(up))
+		if down != "" {
+			mig.down = migrate.NewLocalFile(version+"_change.sql", []byte(down))
+		}
+		return mig
+	}
+
+	tests := []struct {
+		name      string
+		down      string
+		n         int
+		wantErr   error
+		wantTable bool
+	}{
+		{"down script", "DROP TABLE second;", 1, nil, false},
+		{"missing down script", "", 1, ErrDownMigrationMissing, true},
+		{"empty down script", "-- nothing to revert\n", 1, ErrDownMigrationMissing, true},
+		// The first migration has no down script, so neither is reverted
+		{"missing down script further back", "DROP TABLE second;", 2, ErrDownMigrationMissing, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newSQLiteMigrator(t)
+			migrations := []Migration{
+				upDown("1", "CREATE TABLE first (id integer);", ""),
+				upDown("2", "CREATE TABLE second (id integer);", tt.down),
+			}
+			status := applyTestMigrations(t, m, migrations)
+			sets := []migrationSet{{module: sharedModule, migrations: migrations}}
+
+			err := m.revert(context.Background(), sets, newestFirst(status.Applied)[:tt.n])
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("revert() = %v, want %v", err, tt.wantErr)
+			}
+			if got := m.db.Migrator().HasTable("second"); got != tt.wantTable {
+				t.Fatalf("table second exists = %v, want %v", got, tt.wantTable)
+			}
+
+			applied, err := m.readRevisions(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantApplied := 2
+			if tt.wantErr == nil {
+				wantApplied = 2 - tt.n
+			}
+			if len(applied) != wantApplied {
+				t.Fatalf("%d revisions recorded, want %d", len(applied), wantApplied)
+			}
+		})
+	}
+}
+
+type testProvider struct {
+	name      string
+	entities  []any
+	owned     bool
+	dependsOn []string
+}
+
+func (p testProvider) Entities() []any    { return p.entities }
+func (p testProvider) ModuleName() string { return p.name }
+
+// ownedProvider owns an empty migrations directory
+type ownedProvider struct{ testProvider }
+
+func (p ownedProvider) Migrations() fs.FS     { return fstest.MapFS{} }
+func (p ownedProvider) MigrationsDir() string { return "internal/modules/" + p.name + "/migrations" }
+func (p ownedProvider) DependsOn() []string   { return p.dependsOn }
+
+// provider returns p as the module registers it, owning its migrations when
+// p.owned is set
+func (p testProvider) provider() EntityProvider {
+	if p.owned {
+		return ownedProvider{p}
+	}
+	return p
+}
+
+func newTestMigrator(t *testing.T, providers ...testProvider) *Migrator {
+	t.Helper()
+	sm := NewSchemaManager()
+	for _, p := range providers {
+		if err := sm.RegisterProvider(p.provider()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Migrator{
+		schema: sm,
+		config: &Config{},
+		db:     &Database{dialect: postgresDialect},
+	}
+}
+
+func setModules(sets []migrationSet) []string {
+	modules := make([]string, len(sets))
+	for i, set := range sets {
+		modules[i] = moduleLabel(set.module)
+	}
+	return modules
+}
+
+func TestOrderMigrationSets(t *testing.T) {
+	tests := []struct {
+		name    string
+		sets    []migrationSet
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "shared first then by module",
+			sets: []migrationSet{{module: "reports"}, {module: "billing"}, {module: sharedModule}},
+			want: []string{"shared", "billing", "reports"},
+		},
+		{
+			name: "dependencies first",
+			sets: []migrationSet{
+				{module: "billing", dependsOn: []string{"reports"}},
+				{module: "reports", dependsOn: []string{sharedModule}},
+				{module: sharedModule},
+			},
+			want: []string{"shared", "reports", "billing"},
+		},
+		{
+			name: "cycle",
+			sets: []migrationSet{
+				{module: "billing", dependsOn: []string{"reports"}},
+				{module: "reports", dependsOn: []string{"billing"}},
+				{module: sharedModule},
+			},
+			wantErr: ErrMigrationDependencyCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sets := make(map[string]*migrationSet, len(tt.sets))
+			for i := range tt.sets {
+				sets[tt.sets[i].module] = &tt.sets[i]
+			}
+			ordered, err := orderMigrationSets(sets)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("orderMigrationSets() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := setModules(ordered); tt.wantErr == nil && !slices.Equal(got, tt.want) {
+				t.Fatalf("orderMigrationSets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrationSetsDependencies(t *testing.T) {
+	m := newTestMigrator(t,
+		testProvider{name: "auth"},
+		testProvider{name: "reports", owned: true, dependsOn: []string{"billing"}},
+		testProvider{name: "billing", owned: true, dependsOn: []string{"auth"}},
+	)
+	sets, err := m.loadMigrationSets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := setModules(sets), []string{"shared", "billing", "reports"}; !slices.Equal(got, want) {
+		t.Fatalf("loadMigrationSets() = %v, want %v", got, want)
+	}
+
+	m = newTestMigrator(t, testProvider{name: "billing", owned: true, dependsOn: []string{"ledger"}})
+	if _, err := m.loadMigrationSets(); err == nil {
+		t.Fatal("loadMigrationSets() accepted a dependency on an unregistered module")
+	}
+}
+
+func TestMigrationTargets(t *testing.T) {
+	m := newTestMigrator(t,
+		testProvider{name: "auth", entities: []any{"users"}},
+		testProvider{name: "audit", entities: []any{"audit_logs"}},
+		testProvider{name: "billing", entities: []any{"invoices"}, owned: true},
+	)
+
+	tests := []struct {
+		name    string
+		modules []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "all", want: []string{"shared", "billing"}},
+		{name: "owned module", modules: []string{"billing"}, want: []string{"billing"}},
+		{name: "shared module", modules: []string{"audit"}, want: []string{"shared"}},
+		{name: "unregistered module", modules: []string{"ledger"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := m.migrationTargets(tt.modules)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("migrationTargets(%v) error = %v, want error %v", tt.modules, err, tt.wantErr)
+			}
+			got := make([]string, len(targets))
+			for i, target := range targets {
+				got[i] = moduleLabel(target.module)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Fatalf("migrationTargets(%v) = %v, want %v", tt.modules, got, tt.want)
+			}
+		})
+	}
+
+	// The shared directory is diffed against every module sharing it
+	targets, err := m.migrationTargets([]string{"audit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(targets[0].entities); got != 2 {
+		t.Fatalf("shared target has %d entities, want those of auth and audit", got)
+	}
+}
+
+func TestMigrateToRevertsDependents(t *testing.T) {
+	upDown := func(module, version, table string) Migration {
+		mig := testMigration(module, version, version)
+		mig.file = migrate.NewLocalFile(version+"_change.sql", []byte("CREATE TABLE "+table+" (id integer);"))
+		mig.down = migrate.NewLocalFile(version+"_change.sql", []byte("DROP TABLE "+table+";"))
+		return mig
+	}
+	sets := []migrationSet{
+		{module: sharedModule, migrations: []Migration{upDown("", "1", "first"), upDown("", "3", "third")}},
+		{module: "billing", dependsOn: []string{sharedModule}, migrations: []Migration{upDown("billing", "2", "invoices")}},
+	}
+
+	m := newSQLiteMigrator(t)
+	status := func() *MigrationStatus {
+		t.Helper()
+		applied, err := m.readRevisions(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, issues := compareMigrationSets(sets, applied)
+		if len(issues) > 0 {
+			t.Fatal(issues)
+		}
+		return status
+	}
+	applied := func() []string {
+		t.Helper()
+		var got []string
+		for _, rev := range status().Applied {
+			got = append(got, rev.Module+"/"+rev.Version)
+		}
+		return got
+	}
+
+	// Migrating billing up applies the shared migrations it depends on first
+	if err := m.migrateTo(context.Background(), status(), sets, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := applied(), []string{"/1", "/3", "billing/2"}; !slices.Equal(got, want) {
+		t.Fatalf("applied = %v, want %v", got, want)
+	}
+
+	// Migrating the shared directory down reverts billing, applied after 1
+	if err := m.migrateTo(context.Background(), status(), sets, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := applied(), []string{"/1"}; !slices.Equal(got, want) {
+		t.Fatalf("applied = %v, want %v", got, want)
+	}
+	for _, table := range []string{"third", "invoices"} {
+		if m.db.Migrator().HasTable(table) {
+			t.Fatalf("table %s was not dropped", table)
+		}
+	}
+}
+
+func TestCheckMigrationsDialect(t *testing.T) {
+	tests := []struct {
+		name          string
+		dialect       *Dialect
+		migrationsDir string
+		module        string
+		wantErr       bool
+	}{
+		{"postgres shared", postgresDialect, "", sharedModule, false},
+		{"postgres module", postgresDialect, "", "audit", false},
+		{"mysql embedded shared", mysqlDialect, "", sharedModule, true},
+		{"mysql own directory", mysqlDialect, "db/mysql", sharedModule, false},
+		{"mysql module", mysqlDialect, "db/mysql", "audit", true},
+		{"sqlserver embedded shared", sqlserverDialect, "", sharedModule, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{
+				config: &Config{Database: DatabaseConfig{MigrationsDir: tt.migrationsDir}},
+				db:     &Database{dialect: tt.dialect},
+			}
+			err := m.checkMigrationsDialect(tt.module)
+			if tt.wantErr != errors.Is(err, ErrMigrationsUnsupported) {
+				t.Fatalf("checkMigrationsDialect(%q) = %v, want error %v", tt.module, err, tt.wantErr)
+			}
+		})
+	}
+}
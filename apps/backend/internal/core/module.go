@@ -10,21 +10,34 @@ var Module = fx.Module("core",
 	fx.Provide(
 		NewConfig,
 		NewLogger,
+		NewMetricsRegistry,
 		NewDatabase,
+		NewTxManager,
+		NewEventBus,
 		NewSchemaManager,
-		NewMigrator,
+		fx.Annotate(
+			NewMigrator,
+			fx.ParamTags(``, ``, ``, ``, `group:"seeders"`),
+		),
 		NewCache,
+		NewHTTPServer,
+		NewScheduler,
 	),
 	fx.Invoke(registerLifecycleHooks),
 )
 
+// ServeModule migrates the database and starts the long-running parts of the
+// app (HTTP server and scheduled jobs). It is only included by the API binary,
+// not the CLI tools, which inspect and migrate the database on their own.
+var ServeModule = fx.Module("serve",
+	fx.Invoke(registerMigrationHooks, registerServerHooks, registerSchedulerHooks),
+)
+
 func registerLifecycleHooks(
 	lc fx.Lifecycle,
 	cfg *Config,
 	log Logger,
 	db *Database,
-	sm *SchemaManager,
-	m *Migrator,
 	cache Cache,
 ) {
 	lc.Append(fx.Hook{
@@ -37,11 +50,6 @@ func registerLifecycleHooks(
 			if err := db.Health(ctx); err != nil {
 				return err
 			}
-			// Run Migrations
-			log.Info("Running migrations")
-			if err := m.ApplyMigrations(); err != nil {
-				return err
-			}
 
 			log.Info("Core Module started successfully")
 			return nil
@@ -68,3 +76,19 @@ func registerLifecycleHooks(
 		},
 	})
 }
+
+// registerMigrationHooks applies pending migrations before serving, or, when
+// database.migrate_on_start is off, refuses to serve until a separate job has
+// applied them
+func registerMigrationHooks(lc fx.Lifecycle, cfg *Config, log Logger, m *Migrator) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if !cfg.Database.MigrateOnStart {
+				log.Info("Skipping migrations, checking the schema is current")
+				return m.EnsureCurrent(ctx)
+			}
+			log.Info("Running migrations")
+			return m.ApplyMigrations(ctx)
+		},
+	})
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// queryLogger writes GORM queries as structured log entries carrying the
+// duration, affected rows, calling code and request ID of each query
+type queryLogger struct {
+	log           Logger
+	level         logger.LogLevel
+	slowThreshold time.Duration
+	logParams     bool
+}
+
+func newQueryLogger(cfg *Config, log Logger) *queryLogger {
+	return &queryLogger{
+		log:           log,
+		level:         getGormLogLevel(cfg.Logger.Level),
+		slowThreshold: cfg.Database.SlowQueryThreshold,
+		logParams:     cfg.Database.LogQueryParams,
+	}
+}
+
+func (l *queryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *queryLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.log.WithContext(ctx).Info(fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *queryLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.log.WithContext(ctx).Warn(fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *queryLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.log.WithContext(ctx).Error(fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace logs failed queries as errors, queries slower than the threshold as
+// warnings and, at debug level, every other query
+func (l *queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+
+	switch {
+	case failed && l.level >= logger.Error:
+		l.log.WithContext(ctx).Error("Query failed", append(l.fields(fc, elapsed), Error(err))...)
+	case slow && l.level >= logger.Warn:
+		l.log.WithContext(ctx).Warn("Slow query",
+			append(l.fields(fc, elapsed), Duration("threshold", l.slowThreshold))...)
+	case l.level >= logger.Info:
+		l.log.WithContext(ctx).Debug("Query", l.fields(fc, elapsed)...)
+	}
+}
+
+// ParamsFilter keeps bound values out of logged SQL unless enabled, as they
+// may hold personal data
+func (l *queryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.logParams {
+		return sql, params
+	}
+	return sql, nil
+}
+
+func (l *queryLogger) fields(fc func() (string, int64), elapsed time.Duration) []zap.Field {
+	sql, rows := fc()
+	return []zap.Field{
+		String("sql", sql),
+		Duration("duration", elapsed),
+		Int64("rows", rows),
+		String("caller", queryCaller()),
+	}
+}
+
+// queryCaller returns the file and line of the first frame outside GORM and
+// this logger, which is the code that issued the query
+func queryCaller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") && !strings.HasSuffix(frame.File, "core/query_logger.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	ReplicaPolicyRandom     = "random"
+	ReplicaPolicyRoundRobin = "round_robin"
+)
+
+type primaryPinKey struct{}
+
+// primaryPin records whether reads made with a context must use the primary
+type primaryPin struct {
+	pinned atomic.Bool
+}
+
+// WithReadYourWrites returns ctx whose reads switch from the replicas to the
+// primary once a write made with it commits, so a request always sees its own
+// changes despite replication lag.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(primaryPinKey{}).(*primaryPin); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, primaryPinKey{}, &primaryPin{})
+}
+
+// WithPrimary returns ctx whose reads always go to the primary
+func WithPrimary(ctx context.Context) context.Context {
+	pin := &primaryPin{}
+	pin.pinned.Store(true)
+	return context.WithValue(ctx, primaryPinKey{}, pin)
+}
+
+// markWritten pins ctx to the primary if it was set up for read-your-writes
+func markWritten(ctx context.Context) {
+	if pin, ok := ctx.Value(primaryPinKey{}).(*primaryPin); ok {
+		pin.pinned.Store(true)
+	}
+}
+
+func readsFromPrimary(ctx context.Context) bool {
+	pin, ok := ctx.Value(primaryPinKey{}).(*primaryPin)
+	return ok && pin.pinned.Load()
+}
+
+// replica is a read-only connection pool registered with dbresolver
+type replica struct {
+	name string
+	db   *sql.DB
+}
+
+// registerReplicas routes reads to the configured replicas. Writes, locking
+// reads and everything inside a transaction keep using the primary.
+func registerReplicas(db *gorm.DB, cfg *Config, dialect *Dialect) ([]replica, error) {
+	if len(cfg.Database.Replicas) == 0 {
+		return nil, nil
+	}
+
+	replicas := make([]replica, 0, len(cfg.Database.Replicas))
+	dialectors := make([]gorm.Dialector, 0, len(cfg.Database.Replicas))
+	for i, dsn := range cfg.Database.Replicas {
+		sqlDB, err := dialect.openDB(dsn)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, fmt.Errorf("failed to open replica %d: %w", i, err)
+		}
+		replicas = append(replicas, replica{name: fmt.Sprintf("replica-%d", i), db: sqlDB})
+		dialectors = append(dialectors, dialect.OpenConn(sqlDB))
+	}
+
+	var policy dbresolver.Policy = dbresolver.RandomPolicy{}
+	if cfg.Database.ReplicaPolicy == ReplicaPolicyRoundRobin {
+		policy = dbresolver.StrictRoundRobinPolicy()
+	}
+
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas:          dialectors,
+		Policy:            policy,
+		TraceResolverMode: cfg.Logger.Level == "debug",
+	}).
+		SetMaxOpenConns(cfg.Database.MaxOpenConns).
+		SetMaxIdleConns(cfg.Database.MaxIdleConns).
+		SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+
+	if err := db.Use(resolver); err != nil {
+		closeReplicas(replicas)
+		return nil, fmt.Errorf("failed to register read replicas: %w", err)
+	}
+	return replicas, nil
+}
+
+// replicaHealth pings every replica and joins the errors of unreachable ones
+func replicaHealth(ctx context.Context, replicas []replica) error {
+	var errs []error
+	for _, r := range replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		if err := r.db.PingContext(pingCtx); err != nil {
+			errs = append(errs, fmt.Errorf("%s health check failed: %w", r.name, err))
+		}
+		cancel()
+	}
+	return errors.Join(errs...)
+}
+
+func closeReplicas(replicas []replica) error {
+	var errs []error
+	for _, r := range replicas {
+		if err := r.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", r.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
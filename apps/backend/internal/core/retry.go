@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// connectRetryMaxDelay caps the backoff between startup connection attempts
+	connectRetryMaxDelay = 10 * time.Second
+)
+
+type retryContextKey struct{}
+
+// WithRetry returns ctx whose database calls are retried on transient
+// failures. Only opt in for work that is safe to repeat: a connection lost
+// while committing may leave the first attempt committed.
+func WithRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryContextKey{}, true)
+}
+
+func retryEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(retryContextKey{}).(bool)
+	return enabled
+}
+
+// RetryPolicy repeats an operation with exponential backoff and jitter
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt; 1 or less disables retries
+	MaxAttempts int
+	BaseDelay   time.Duration
+	// MaxDelay caps the backoff; zero leaves it unbounded
+	MaxDelay time.Duration
+}
+
+// Do runs fn until it succeeds, fails with an error retryable rejects,
+// MaxAttempts is reached or ctx ends. Each retry is logged as a warning.
+func (p RetryPolicy) Do(
+	ctx context.Context,
+	log Logger,
+	operation string,
+	retryable func(error) bool,
+	fn func(ctx context.Context) error,
+) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := p.backoff(attempt - 1)
+		log.WithContext(ctx).Warn("Retrying database operation",
+			String("operation", operation),
+			Int("attempt", attempt),
+			Duration("delay", delay),
+			Error(err))
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff doubles BaseDelay per retry and adds up to as much again in jitter
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	return retryDelay(p.BaseDelay, p.MaxDelay, retry)
+}
+
+func retryDelay(base, maxDelay time.Duration, retry int) time.Duration {
+	delay := base << min(retry, 30)
+	if maxDelay > 0 && (delay > maxDelay || delay <= 0) {
+		delay = maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	delay += time.Duration(rand.Int64N(int64(delay)))
+	if maxDelay > 0 {
+		delay = min(delay, maxDelay)
+	}
+	return delay
+}
+
+// IsTransientError reports whether err is a failure a later attempt may not
+// hit: a serialization failure, a deadlock or a lost connection
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if IsRetryableTxError(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, sqlStateClassConnection) ||
+			pgErr.Code == sqlStateAdminShutdown ||
+			pgErr.Code == sqlStateCannotConnect
+	}
+
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) ||
+		pgconn.SafeToRetry(err) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// Retry runs fn again on transient failures when ctx opted in with WithRetry.
+// Calls inside a transaction are never retried on their own, as the failure
+// aborted the whole transaction.
+func (db *Database) Retry(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	if !retryEnabled(ctx) {
+		return fn(ctx)
+	}
+	if _, inTx := TxFromContext(ctx); inTx {
+		return fn(ctx)
+	}
+	return db.retry.Do(ctx, db.log, operation, IsTransientError, fn)
+}
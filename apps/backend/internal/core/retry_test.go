@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		maxDelay time.Duration
+		retry    int
+		min, max time.Duration
+	}{
+		{"first retry", 10 * time.Millisecond, 0, 0, 10 * time.Millisecond, 20*time.Millisecond - 1},
+		{"doubles per retry", 10 * time.Millisecond, 0, 2, 40 * time.Millisecond, 80*time.Millisecond - 1},
+		{"capped", time.Second, 2 * time.Second, 5, 2 * time.Second, 2 * time.Second},
+		{"jitter capped", time.Second, 1500 * time.Millisecond, 0, time.Second, 1500 * time.Millisecond},
+		{"shift overflow capped", time.Second, time.Minute, 100, time.Minute, time.Minute},
+		{"no base", 0, 0, 3, 0, 0},
+		{"no base capped", 0, time.Second, 3, time.Second, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The delay is jittered, so sample it
+			for range 100 {
+				got := retryDelay(tt.base, tt.maxDelay, tt.retry)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("retryDelay() = %s, want within [%s, %s]", got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestIsConnectRetryable(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", fmt.Errorf("failed to ping database: %w", refused), true},
+		{"host not resolved yet", &net.DNSError{Err: "no such host", Name: "db", IsNotFound: true}, true},
+		{"ping timed out", fmt.Errorf("failed to ping database: %w", context.DeadlineExceeded), true},
+		{"starting up", &pgconn.PgError{Code: sqlStateCannotConnect}, true},
+		{"wrong password", &pgconn.PgError{Code: "28P01"}, false},
+		{"unknown database", &pgconn.PgError{Code: "3D000"}, false},
+		{"other", errors.New("unsupported database type"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnectRetryable(tt.err); got != tt.want {
+				t.Fatalf("isConnectRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
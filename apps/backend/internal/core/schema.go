@@ -3,9 +3,14 @@ package core
 import (
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
+	"slices"
 	"sync"
 
 	"ariga.io/atlas-provider-gorm/gormschema"
+	"ariga.io/atlas/sdk/recordriver"
+	"gorm.io/gorm"
 )
 
 // EntityProvider defines the interface for modules to provide their entities
@@ -14,6 +19,31 @@ type EntityProvider interface {
 	ModuleName() string
 }
 
+// MigrationProvider is implemented by entity providers whose module owns its
+// migrations directory and history, instead of sharing the migrations
+// directory with the other modules. Its entities cannot reference the tables
+// of other modules, which the dev database lacks when the module is diffed.
+type MigrationProvider interface {
+	// Migrations holds the module's migrations, atlas.sum and down scripts,
+	// usually embedded from MigrationsDir
+	Migrations() fs.FS
+	// MigrationsDir is where generate writes the module's migrations, relative
+	// to the backend directory
+	MigrationsDir() string
+}
+
+// DependentProvider is implemented by entity providers whose migrations need
+// the tables of other modules, which are migrated first
+type DependentProvider interface {
+	DependsOn() []string
+}
+
+// ChangeProvider is implemented by entity providers renaming or retyping
+// columns of their tables without downtime
+type ChangeProvider interface {
+	ColumnChanges() []ColumnChange
+}
+
 // SchemaManager manages all entities from different modules
 type SchemaManager struct {
 	mu        sync.RWMutex
@@ -42,6 +72,18 @@ func (sm *SchemaManager) RegisterProvider(provider EntityProvider) error {
 	return nil
 }
 
+// Providers returns the registered entity providers ordered by module name
+func (sm *SchemaManager) Providers() []EntityProvider {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	providers := make([]EntityProvider, 0, len(sm.providers))
+	for _, name := range slices.Sorted(maps.Keys(sm.providers)) {
+		providers = append(providers, sm.providers[name])
+	}
+	return providers
+}
+
 // GetAllEntities returns all registered entities from all modules
 func (sm *SchemaManager) GetAllEntities() []interface{} {
 	sm.mu.RLock()
@@ -94,34 +136,37 @@ func (sm *SchemaManager) GetModuleInfo() map[string]int {
 	return info
 }
 
-// LoadGORMSchema loads all entities  and outputs Atlas HCL schema
-func (sm *SchemaManager) LoadGORMSchema(writer io.Writer, cfg *Config, db *Database) error {
+// LoadGORMSchema writes the DDL of entities for the configured dialect
+func (sm *SchemaManager) LoadGORMSchema(writer io.Writer, cfg *Config, entities []any) error {
 	fmt.Println("Loading gorm models started")
 
-	entities := sm.GetAllEntities()
-
 	if len(entities) == 0 {
 		return fmt.Errorf("no entities registered")
 	}
 
-	var driverName string
-	switch cfg.Database.Type {
-	case "postgres", "postgresql":
-		driverName = "postgres"
-	case "mysql":
-		driverName = "mysql"
-	default:
-		return fmt.Errorf("unsupported database type: %s", cfg.Database.Type)
+	dialect, err := cfg.Dialect()
+	if err != nil {
+		return err
 	}
 
-	// Convert GORM schema to Atlas HCL format
-	// Pass the underlying gorm.DB (not the Database wrapper)
-	stmts, err := gormschema.New(driverName).Load(entities...)
+	// Convert GORM schema to Atlas HCL format, with the Postgres column types
+	// of entity tags adapted to the target dialect
+	loader := gormschema.New(dialect.Name, gormschema.WithConfig(&gorm.Config{
+		Plugins: map[string]gorm.Plugin{
+			schemaAdapter{}.Name(): schemaAdapter{dialect: dialect, models: entities},
+		},
+	}))
+	// gormschema records into a session that outlives each load, clear what
+	// earlier loads of this process recorded
+	if session, ok := recordriver.Session("gorm"); ok {
+		session.Statements = nil
+	}
+	stmts, err := loader.Load(entities...)
 	if err != nil {
 		return fmt.Errorf("failed to convert schema to Atlas format: %w", err)
 	}
 
-	stmts = fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\";\n%s", stmts)
+	stmts = dialect.schemaPreamble + stmts
 	_, err = writer.Write([]byte(stmts))
 	if err != nil {
 		return fmt.Errorf("failed to write schema to file: %w", err)
@@ -0,0 +1,357 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"gorm.io/gorm/schema"
+)
+
+// schemaDocsDir is where the docs action writes the Markdown docs and the ER
+// diagrams of the entities, committed with the migrations
+const schemaDocsDir = "schema/docs"
+
+// mermaidCardinality draws each kind of relation in a Mermaid ER diagram
+var mermaidCardinality = map[schema.RelationshipType]string{
+	schema.HasOne:    "||--o|",
+	schema.HasMany:   "||--o{",
+	schema.BelongsTo: "}o--||",
+	schema.Many2Many: "}o--o{",
+}
+
+// EntityDoc describes the table of a registered entity
+type EntityDoc struct {
+	Module string `json:"module"`
+	// Name is the Go type of the entity
+	Name      string        `json:"name"`
+	Table     string        `json:"table"`
+	Fields    []FieldDoc    `json:"fields"`
+	Indexes   []IndexDoc    `json:"indexes"`
+	Relations []RelationDoc `json:"relations"`
+}
+
+// FieldDoc describes a column of an entity table
+type FieldDoc struct {
+	Name       string `json:"name"`
+	Column     string `json:"column"`
+	Type       string `json:"type"`
+	PrimaryKey bool   `json:"primary_key"`
+	ForeignKey bool   `json:"foreign_key"`
+	NotNull    bool   `json:"not_null"`
+	Unique     bool   `json:"unique"`
+	Default    string `json:"default"`
+}
+
+// IndexDoc describes an index of an entity table
+type IndexDoc struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+}
+
+// RelationDoc describes an association field of an entity
+type RelationDoc struct {
+	Field string `json:"field"`
+	// Kind is has_one, has_many, belongs_to or many_to_many
+	Kind  string `json:"kind"`
+	Table string `json:"table"`
+	// Through is the join table of many_to_many relations
+	Through string `json:"through,omitempty"`
+	// ForeignKeys are the referencing columns, as table.column
+	ForeignKeys []string `json:"foreign_keys"`
+}
+
+// Document parses the registered entities with GORM, ordered by module and
+// table
+func (sm *SchemaManager) Document() ([]EntityDoc, error) {
+	cache := &sync.Map{}
+	var docs []EntityDoc
+	for _, provider := range sm.Providers() {
+		var moduleDocs []EntityDoc
+		for _, entity := range provider.Entities() {
+			parsed, err := schema.Parse(entity, cache, schema.NamingStrategy{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %T: %w", entity, err)
+			}
+			moduleDocs = append(moduleDocs, documentEntity(provider.ModuleName(), parsed))
+		}
+		slices.SortFunc(moduleDocs, func(a, b EntityDoc) int { return strings.Compare(a.Table, b.Table) })
+		docs = append(docs, moduleDocs...)
+	}
+
+	// Foreign keys are declared by the relations of either side
+	foreignKeys := make(map[string]bool)
+	for _, doc := range docs {
+		for _, rel := range doc.Relations {
+			for _, fk := range rel.ForeignKeys {
+				foreignKeys[fk] = true
+			}
+		}
+	}
+	for _, doc := range docs {
+		for i, field := range doc.Fields {
+			doc.Fields[i].ForeignKey = foreignKeys[doc.Table+"."+field.Column]
+		}
+	}
+	return docs, nil
+}
+
+func documentEntity(module string, parsed *schema.Schema) EntityDoc {
+	doc := EntityDoc{
+		Module: module,
+		Name:   parsed.ModelType.String(),
+		Table:  parsed.Table,
+	}
+
+	for _, field := range parsed.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		doc.Fields = append(doc.Fields, FieldDoc{
+			Name:       field.Name,
+			Column:     field.DBName,
+			Type:       fieldType(field),
+			PrimaryKey: field.PrimaryKey,
+			NotNull:    field.NotNull || field.PrimaryKey,
+			Unique:     field.Unique,
+			Default:    field.DefaultValue,
+		})
+	}
+
+	for _, index := range parsed.ParseIndexes() {
+		columns := make([]string, len(index.Fields))
+		for i, option := range index.Fields {
+			columns[i] = option.DBName
+		}
+		doc.Indexes = append(doc.Indexes, IndexDoc{Name: index.Name, Columns: columns, Unique: index.Class == "UNIQUE"})
+	}
+	slices.SortFunc(doc.Indexes, func(a, b IndexDoc) int { return strings.Compare(a.Name, b.Name) })
+
+	for _, name := range slices.Sorted(maps.Keys(parsed.Relationships.Relations)) {
+		rel := parsed.Relationships.Relations[name]
+		if rel.Schema.ModelType != parsed.ModelType {
+			// Back references GORM records on the schemas of related entities
+			continue
+		}
+		relation := RelationDoc{Field: rel.Name, Kind: string(rel.Type), Table: rel.FieldSchema.Table}
+		if rel.JoinTable != nil {
+			relation.Through = rel.JoinTable.Table
+		}
+		for _, ref := range rel.References {
+			if ref.ForeignKey != nil {
+				relation.ForeignKeys = append(relation.ForeignKeys, ref.ForeignKey.Schema.Table+"."+ref.ForeignKey.DBName)
+			}
+		}
+		doc.Relations = append(doc.Relations, relation)
+	}
+	return doc
+}
+
+// fieldType is the column type of the field tag, or its GORM data type with
+// the size of strings
+func fieldType(field *schema.Field) string {
+	if t := field.TagSettings["TYPE"]; t != "" {
+		return strings.ToLower(t)
+	}
+	if field.DataType == schema.String && field.Size > 0 {
+		return fmt.Sprintf("%s(%d)", field.DataType, field.Size)
+	}
+	return string(field.DataType)
+}
+
+// WriteSchemaDocs writes the Markdown docs and the Mermaid and Graphviz ER
+// diagrams of the registered entities to schema/docs
+func (m *Migrator) WriteSchemaDocs() error {
+	docs, err := m.schema.Document()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(schemaDocsDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create schema docs directory: %w", err)
+	}
+
+	for name, write := range map[string]func(io.Writer, []EntityDoc){
+		"schema.md":  writeSchemaMarkdown,
+		"schema.mmd": writeSchemaMermaid,
+		"schema.dot": writeSchemaGraphviz,
+	} {
+		var buf bytes.Buffer
+		write(&buf, docs)
+		path := filepath.Join(schemaDocsDir, name)
+		if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	m.log.Info("Schema docs written", String("dir", schemaDocsDir), Int("entities", len(docs)))
+	return nil
+}
+
+func writeSchemaMarkdown(w io.Writer, docs []EntityDoc) {
+	fmt.Fprintln(w, "# Schema")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Generated from the registered entities by `go run ./cmd/schema -action=docs`, do not edit.")
+
+	module := "-"
+	for _, doc := range docs {
+		if doc.Module != module {
+			module = doc.Module
+			fmt.Fprintf(w, "\n## %s\n", module)
+		}
+		fmt.Fprintf(w, "\n### %s\n\n`%s`\n\n", doc.Table, doc.Name)
+
+		fmt.Fprintln(w, "| Column | Type | Null | Key | Default |")
+		fmt.Fprintln(w, "|---|---|---|---|---|")
+		for _, field := range doc.Fields {
+			null := "NULL"
+			if field.NotNull {
+				null = "NOT NULL"
+			}
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n",
+				field.Column, markdownCell(field.Type), null, strings.Join(fieldKeys(field), ", "), markdownCell(field.Default))
+		}
+
+		if len(doc.Indexes) > 0 {
+			fmt.Fprintln(w, "\n| Index | Columns | Unique |")
+			fmt.Fprintln(w, "|---|---|---|")
+			for _, index := range doc.Indexes {
+				unique := ""
+				if index.Unique {
+					unique = "yes"
+				}
+				fmt.Fprintf(w, "| %s | %s | %s |\n", index.Name, strings.Join(index.Columns, ", "), unique)
+			}
+		}
+
+		if len(doc.Relations) > 0 {
+			fmt.Fprintln(w, "\n| Relation | Kind | Table | Foreign keys |")
+			fmt.Fprintln(w, "|---|---|---|---|")
+			for _, rel := range doc.Relations {
+				table := rel.Table
+				if rel.Through != "" {
+					table += " through " + rel.Through
+				}
+				fmt.Fprintf(w, "| %s | %s | %s | %s |\n", rel.Field, rel.Kind, table, strings.Join(rel.ForeignKeys, ", "))
+			}
+		}
+	}
+
+	fmt.Fprintln(w, "\n## Diagram")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "```mermaid")
+	writeSchemaMermaid(w, docs)
+	fmt.Fprintln(w, "```")
+}
+
+func writeSchemaMermaid(w io.Writer, docs []EntityDoc) {
+	fmt.Fprintln(w, "erDiagram")
+	module := "-"
+	for _, doc := range docs {
+		if doc.Module != module {
+			module = doc.Module
+			fmt.Fprintf(w, "    %%%% module %s\n", module)
+		}
+		fmt.Fprintf(w, "    %s {\n", doc.Table)
+		for _, field := range doc.Fields {
+			fmt.Fprintf(w, "        %s %s", mermaidType(field.Type), field.Column)
+			if keys := fieldKeys(field); len(keys) > 0 {
+				fmt.Fprintf(w, " %s", strings.Join(keys, ", "))
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "    }")
+	}
+	for _, doc := range docs {
+		for _, rel := range doc.Relations {
+			fmt.Fprintf(w, "    %s %s %s : %q\n",
+				doc.Table, mermaidCardinality[schema.RelationshipType(rel.Kind)], rel.Table, rel.Field)
+		}
+	}
+}
+
+func writeSchemaGraphviz(w io.Writer, docs []EntityDoc) {
+	fmt.Fprintln(w, "digraph schema {")
+	fmt.Fprintln(w, "\tgraph [rankdir=LR, fontname=\"Helvetica\"];")
+	fmt.Fprintln(w, "\tnode [shape=record, fontname=\"Helvetica\", fontsize=10];")
+	fmt.Fprintln(w, "\tedge [fontname=\"Helvetica\", fontsize=9];")
+
+	for i := 0; i < len(docs); {
+		module := docs[i].Module
+		fmt.Fprintf(w, "\n\tsubgraph %q {\n\t\tlabel=%q;\n", "cluster_"+module, module)
+		for ; i < len(docs) && docs[i].Module == module; i++ {
+			var label strings.Builder
+			label.WriteString("{" + recordEscape(docs[i].Table) + "|")
+			for _, field := range docs[i].Fields {
+				label.WriteString(recordEscape(field.Column + " : " + field.Type))
+				if keys := fieldKeys(field); len(keys) > 0 {
+					label.WriteString(recordEscape(" (" + strings.Join(keys, ", ") + ")"))
+				}
+				label.WriteString(`\l`)
+			}
+			label.WriteString("}")
+			fmt.Fprintf(w, "\t\t%q [label=\"%s\"];\n", docs[i].Table, label.String())
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+
+	fmt.Fprintln(w)
+	for _, doc := range docs {
+		for _, rel := range doc.Relations {
+			fmt.Fprintf(w, "\t%q -> %q [label=%q];\n", doc.Table, rel.Table, rel.Field+" ("+rel.Kind+")")
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// fieldKeys lists the PK, FK and UK markers of field
+func fieldKeys(field FieldDoc) []string {
+	var keys []string
+	if field.PrimaryKey {
+		keys = append(keys, "PK")
+	}
+	if field.ForeignKey {
+		keys = append(keys, "FK")
+	}
+	if field.Unique {
+		keys = append(keys, "UK")
+	}
+	return keys
+}
+
+// mermaidType drops the arguments of a column type and the characters Mermaid
+// does not accept in attribute types
+func mermaidType(t string) string {
+	if i := strings.IndexByte(t, '('); i >= 0 {
+		t = t[:i]
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(t))
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// recordEscape escapes the characters Graphviz record labels give a meaning to
+func recordEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`{}|<>"\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type docAuthor struct {
+	ID    uint      `gorm:"primaryKey"`
+	Name  string    `gorm:"size:64;not null;uniqueIndex"`
+	Email string    `gorm:"size:128;unique"`
+	Books []docBook `gorm:"foreignKey:AuthorID"`
+}
+
+type docBook struct {
+	ID       uint   `gorm:"primaryKey"`
+	Title    string `gorm:"type:varchar(200)"`
+	Status   string `gorm:"size:16;default:'draft|published'"`
+	AuthorID uint   `gorm:"not null;index"`
+}
+
+func testSchemaDocs(t *testing.T) []EntityDoc {
+	t.Helper()
+	sm := NewSchemaManager()
+	provider := testProvider{name: "library", entities: []any{&docBook{}, &docAuthor{}}}
+	if err := sm.RegisterProvider(provider); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := sm.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return docs
+}
+
+func TestDocumentEntity(t *testing.T) {
+	docs := testSchemaDocs(t)
+	if len(docs) != 2 || docs[0].Table != "doc_authors" || docs[1].Table != "doc_books" {
+		t.Fatalf("Document() = %+v, want doc_authors then doc_books", docs)
+	}
+	authors, books := docs[0], docs[1]
+
+	if len(authors.Relations) != 1 {
+		t.Fatalf("relations = %+v, want Books", authors.Relations)
+	}
+	if rel := authors.Relations[0]; rel.Kind != "has_many" || rel.Table != "doc_books" ||
+		len(rel.ForeignKeys) != 1 || rel.ForeignKeys[0] != "doc_books.author_id" {
+		t.Errorf("relation = %+v, want has_many doc_books by doc_books.author_id", rel)
+	}
+	if len(authors.Indexes) != 1 || !authors.Indexes[0].Unique || authors.Indexes[0].Columns[0] != "name" {
+		t.Errorf("indexes = %+v, want a unique index of name", authors.Indexes)
+	}
+
+	fields := make(map[string]FieldDoc)
+	for _, field := range books.Fields {
+		fields[field.Column] = field
+	}
+	tests := []struct {
+		column string
+		want   FieldDoc
+	}{
+		{"id", FieldDoc{Name: "ID", Column: "id", Type: "uint", PrimaryKey: true, NotNull: true}},
+		{"title", FieldDoc{Name: "Title", Column: "title", Type: "varchar(200)"}},
+		{"status", FieldDoc{Name: "Status", Column: "status", Type: "string(16)", Default: "draft|published"}},
+		// The foreign key is declared by the relation of the other side
+		{"author_id", FieldDoc{Name: "AuthorID", Column: "author_id", Type: "uint", ForeignKey: true, NotNull: true}},
+	}
+	for _, tt := range tests {
+		if got := fields[tt.column]; got != tt.want {
+			t.Errorf("field %s = %+v, want %+v", tt.column, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSchemaMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	writeSchemaMarkdown(&buf, testSchemaDocs(t))
+	out := buf.String()
+
+	for _, want := range []string{
+		"\n## library\n",
+		"\n### doc_books\n\n`core.docBook`\n",
+		"| id | uint | NOT NULL | PK |  |\n",
+		"| status | string(16) | NULL |  | draft\\|published |\n",
+		"| author_id | uint | NOT NULL | FK |  |\n",
+		"| email | string(128) | NULL | UK |  |\n",
+		"| idx_doc_authors_name | name | yes |\n",
+		"| Books | has_many | doc_books | doc_books.author_id |\n",
+		"```mermaid\nerDiagram\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "\n## library\n") != 1 {
+		t.Errorf("module heading repeated:\n%s", out)
+	}
+}
+
+func TestWriteSchemaMermaid(t *testing.T) {
+	var buf bytes.Buffer
+	writeSchemaMermaid(&buf, testSchemaDocs(t))
+
+	want := `erDiagram
+    %% module library
+    doc_authors {
+        uint id PK
+        string name
+        string email UK
+    }
+    doc_books {
+        uint id PK
+        varchar title
+        string status
+        uint author_id FK
+    }
+    doc_authors ||--o{ doc_books : "Books"
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("Mermaid diagram =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMermaidType(t *testing.T) {
+	tests := map[string]string{
+		"varchar(200)":             "varchar",
+		"string(64)":               "string",
+		"timestamp with time zone": "timestamp_with_time_zone",
+		"numeric (10,2)":           "numeric",
+	}
+	for in, want := range tests {
+		if got := mermaidType(in); got != want {
+			t.Errorf("mermaidType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"ariga.io/atlas/sql/migrate"
+	"gorm.io/gorm"
+)
+
+// driftSchema is the schema DDL is replayed into, rolled back after inspection
+const driftSchema = "schema_drift"
+
+// Kinds of SchemaDrift
+const (
+	driftTable  = "table"
+	driftColumn = "column"
+	driftIndex  = "index"
+)
+
+// driftIgnoredTables are bookkeeping tables no entity or migration declares
+var driftIgnoredTables = []string{
+	SchemaRevision{}.TableName(),
+	atlasRevisionsTable,
+	SeedRecord{}.TableName(),
+	BackfillRecord{}.TableName(),
+}
+
+// reConcurrently matches index builds that cannot run in a transaction; the
+// replayed tables are empty, so they are built the plain way instead
+var reConcurrently = regexp.MustCompile(`(?i)\bINDEX\s+CONCURRENTLY\b`)
+
+// SchemaDrift is a table, column or index that one schema has and another
+// lacks
+type SchemaDrift struct {
+	Kind string `json:"kind"`
+	// Name is the table, or the table and the column or index joined by a dot
+	Name string `json:"name"`
+	// Missing is set when the compared schema lacks the object, and unset when
+	// only the compared schema has it
+	Missing bool `json:"missing"`
+}
+
+// DriftReport compares the schema of the entities with the migrations, and the
+// live database with the migrations applied to it
+type DriftReport struct {
+	// Migrations is how replaying every migration differs from the entities
+	Migrations []SchemaDrift `json:"migrations"`
+	// Live is how the live database differs from its applied migrations
+	Live []SchemaDrift `json:"live"`
+}
+
+// schemaObjects holds the tables of a schema and their columns and indexes,
+// keyed by table and by table.name
+type schemaObjects struct {
+	tables  map[string]bool
+	columns map[string]bool
+	indexes map[string]bool
+}
+
+// Drift compares the DDL of the entities, the migrations replayed from
+// scratch and the live database. Replays run in rolled back transactions, so
+// the database is not changed.
+func (m *Migrator) Drift(ctx context.Context) (*DriftReport, error) {
+	if m.db.Dialect().drift.isolateSchema == nil {
+		return nil, fmt.Errorf("schema drift cannot be detected on %s databases", m.db.Dialect().Name)
+	}
+
+	var ddl bytes.Buffer
+	if err := m.schema.LoadGORMSchema(&ddl, m.config, m.schema.GetAllEntities()); err != nil {
+		return nil, err
+	}
+	entityStmts, err := migrate.Stmts(ddl.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse entity schema: %w", err)
+	}
+	entities, err := m.replaySchema(ctx, "entities", stmtTexts(entityStmts))
+	if err != nil {
+		return nil, err
+	}
+
+	sets, err := m.loadMigrationSets()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedRevisions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appliedRevisions := make(map[string]bool, len(applied))
+	for _, rev := range applied {
+		appliedRevisions[modulePath(rev.Module, rev.Version)] = true
+	}
+	var allStmts, appliedStmts []string
+	for _, set := range sets {
+		for _, mig := range set.migrations {
+			stmts, err := mig.file.Stmts()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse migration %s: %w", mig.path(), err)
+			}
+			allStmts = append(allStmts, stmts...)
+			if appliedRevisions[modulePath(mig.Module, mig.Version)] {
+				appliedStmts = append(appliedStmts, stmts...)
+			}
+		}
+	}
+
+	replayed, err := m.replaySchema(ctx, "migrations", allStmts)
+	if err != nil {
+		return nil, err
+	}
+	replayedApplied, err := m.replaySchema(ctx, "applied migrations", appliedStmts)
+	if err != nil {
+		return nil, err
+	}
+	live, err := m.inspectSchema(m.migrationConn(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &DriftReport{
+		Migrations: diffSchemas(entities, replayed),
+		Live:       diffSchemas(replayedApplied, live),
+	}, nil
+}
+
+// replaySchema runs stmts in a new schema and inspects it, in a transaction
+// that is rolled back
+func (m *Migrator) replaySchema(ctx context.Context, source string, stmts []string) (*schemaObjects, error) {
+	tx := m.migrationConn(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.Rollback()
+
+	if err := m.db.Dialect().drift.isolateSchema(tx, driftSchema); err != nil {
+		return nil, fmt.Errorf("failed to create schema to replay %s: %w", source, err)
+	}
+	for i, stmt := range stmts {
+		if err := tx.Exec(reConcurrently.ReplaceAllString(stmt, "INDEX")).Error; err != nil {
+			return nil, fmt.Errorf("failed to replay %s at statement %d: %w", source, i+1, err)
+		}
+	}
+	return m.inspectSchema(tx)
+}
+
+// inspectSchema lists the tables, columns and indexes of the current schema
+// of conn
+func (m *Migrator) inspectSchema(conn *gorm.DB) (*schemaObjects, error) {
+	type object struct {
+		TableName string
+		Name      string
+	}
+	var columns, indexes []object
+	if err := conn.Raw(m.db.Dialect().drift.columns).Scan(&columns).Error; err != nil {
+		return nil, fmt.Errorf("failed to inspect columns: %w", err)
+	}
+	if err := conn.Raw(m.db.Dialect().drift.indexes).Scan(&indexes).Error; err != nil {
+		return nil, fmt.Errorf("failed to inspect indexes: %w", err)
+	}
+
+	objects := &schemaObjects{
+		tables:  make(map[string]bool),
+		columns: make(map[string]bool),
+		indexes: make(map[string]bool),
+	}
+	for _, c := range columns {
+		if !slices.Contains(driftIgnoredTables, c.TableName) {
+			objects.tables[c.TableName] = true
+			objects.columns[c.TableName+"."+c.Name] = true
+		}
+	}
+	for _, i := range indexes {
+		if !slices.Contains(driftIgnoredTables, i.TableName) {
+			objects.indexes[i.TableName+"."+i.Name] = true
+		}
+	}
+	return objects, nil
+}
+
+// diffSchemas lists the objects got lacks or only got has, compared with
+// want. The columns and indexes of a table only one of them has are left out.
+func diffSchemas(want, got *schemaObjects) []SchemaDrift {
+	var drift []SchemaDrift
+	diff := func(kind string, wantNames, gotNames map[string]bool) {
+		shared := func(name string) bool {
+			return kind == driftTable || want.tables[tableOf(name)] && got.tables[tableOf(name)]
+		}
+		for _, name := range slices.Sorted(maps.Keys(wantNames)) {
+			if !gotNames[name] && shared(name) {
+				drift = append(drift, SchemaDrift{Kind: kind, Name: name, Missing: true})
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(gotNames)) {
+			if !wantNames[name] && shared(name) {
+				drift = append(drift, SchemaDrift{Kind: kind, Name: name})
+			}
+		}
+	}
+	diff(driftTable, want.tables, got.tables)
+	diff(driftColumn, want.columns, got.columns)
+	diff(driftIndex, want.indexes, got.indexes)
+	return drift
+}
+
+func tableOf(name string) string {
+	table, _, _ := strings.Cut(name, ".")
+	return table
+}
+
+func stmtTexts(stmts []*migrate.Stmt) []string {
+	texts := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		texts[i] = stmt.Text
+	}
+	return texts
+}
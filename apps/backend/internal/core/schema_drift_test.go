@@ -0,0 +1,73 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+// testSchema builds schemaObjects from table.column and table.index names
+func testSchema(columns []string, indexes ...string) *schemaObjects {
+	objects := &schemaObjects{
+		tables:  make(map[string]bool),
+		columns: make(map[string]bool),
+		indexes: make(map[string]bool),
+	}
+	for _, name := range columns {
+		objects.tables[tableOf(name)] = true
+		objects.columns[name] = true
+	}
+	for _, name := range indexes {
+		objects.indexes[name] = true
+	}
+	return objects
+}
+
+func TestDiffSchemas(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got *schemaObjects
+		drift     []SchemaDrift
+	}{
+		{
+			name:  "identical",
+			want:  testSchema([]string{"users.id", "users.email"}, "users.idx_users_email"),
+			got:   testSchema([]string{"users.id", "users.email"}, "users.idx_users_email"),
+			drift: nil,
+		},
+		{
+			name: "missing and extra columns are sorted",
+			want: testSchema([]string{"users.id", "users.email", "users.name"}),
+			got:  testSchema([]string{"users.id", "users.nickname"}),
+			drift: []SchemaDrift{
+				{Kind: driftColumn, Name: "users.email", Missing: true},
+				{Kind: driftColumn, Name: "users.name", Missing: true},
+				{Kind: driftColumn, Name: "users.nickname"},
+			},
+		},
+		{
+			name: "indexes",
+			want: testSchema([]string{"users.id"}, "users.idx_users_email"),
+			got:  testSchema([]string{"users.id"}, "users.idx_users_name"),
+			drift: []SchemaDrift{
+				{Kind: driftIndex, Name: "users.idx_users_email", Missing: true},
+				{Kind: driftIndex, Name: "users.idx_users_name"},
+			},
+		},
+		{
+			name: "tables only one side has hide their columns and indexes",
+			want: testSchema([]string{"users.id", "orders.id"}, "orders.idx_orders_user"),
+			got:  testSchema([]string{"users.id", "events.id"}, "events.idx_events_at"),
+			drift: []SchemaDrift{
+				{Kind: driftTable, Name: "orders", Missing: true},
+				{Kind: driftTable, Name: "events"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffSchemas(tt.want, tt.got); !slices.Equal(got, tt.drift) {
+				t.Fatalf("diffSchemas() = %+v, want %+v", got, tt.drift)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Seed loads data a module needs, such as default roles, or demo data. It runs
+// once per database in the environments it is tagged with.
+type Seed struct {
+	// Name identifies the seed within its module
+	Name string
+	// Environments the seed runs in, every environment when empty
+	Environments []string
+	// Run loads the data in the transaction recording the seed. It keeps rows
+	// that are already there, so seeding a database filled by hand is safe.
+	Run func(ctx context.Context, tx *gorm.DB) error
+}
+
+// Seeder is provided by modules with seeds, in the "seeders" group
+type Seeder interface {
+	ModuleName() string
+	// Seeds run in order, so a seed may use the rows of those before it
+	Seeds() []Seed
+}
+
+// SeedRecord records a seed that ran against the database
+type SeedRecord struct {
+	Module      string    `gorm:"size:64;primaryKey"  json:"module"`
+	Name        string    `gorm:"size:128;primaryKey" json:"name"`
+	Environment string    `gorm:"size:32;not null"    json:"environment"`
+	RanAt       time.Time `gorm:"not null"            json:"ran_at"`
+	ExecutionMs int64     `gorm:"not null;default:0"  json:"execution_ms"`
+}
+
+func (SeedRecord) TableName() string { return "schema_seeds" }
+
+// SeedDatabase runs the pending seeds of the modules of moduleFilter, every
+// module when it is empty. env, when set, must be app.environment: seeds only
+// run against the database configured for their environment.
+func (m *Migrator) SeedDatabase(env, moduleFilter string) error {
+	if env != "" && env != m.config.App.Environment {
+		return fmt.Errorf("cannot seed %s data, the configured database is that of app.environment %s",
+			env, m.config.App.Environment)
+	}
+	env = m.config.App.Environment
+	var modules []string
+	if moduleFilter != "" {
+		for _, mod := range strings.Split(moduleFilter, ",") {
+			modules = append(modules, strings.TrimSpace(mod))
+		}
+	}
+
+	m.log.Info("Seeding database", String("env", env), String("modules", moduleFilter))
+	ran, err := m.Seed(context.Background(), modules)
+	if err != nil {
+		m.log.Error("Seeding failed", Error(err))
+		return err
+	}
+
+	if len(ran) == 0 {
+		fmt.Println("\n No pending seeds")
+		return nil
+	}
+	fmt.Printf("\n Ran %d seeds:\n", len(ran))
+	for _, record := range ran {
+		fmt.Printf("  %-10s %-25s %dms\n", record.Module, record.Name, record.ExecutionMs)
+	}
+	return nil
+}
+
+// Seed runs the seeds of modules tagged with app.environment that have not run
+// yet, those of every module when modules is empty, while holding the
+// migration lock. It returns the seeds it ran.
+func (m *Migrator) Seed(ctx context.Context, modules []string) (ran []SeedRecord, err error) {
+	env := m.config.App.Environment
+	registered := m.schema.ListModules()
+	for _, name := range modules {
+		if !slices.Contains(registered, name) {
+			return nil, fmt.Errorf("module %s is not registered", name)
+		}
+	}
+
+	seeders := slices.SortedFunc(slices.Values(m.seeders), func(a, b Seeder) int {
+		return strings.Compare(a.ModuleName(), b.ModuleName())
+	})
+	err = m.withMigrationLock(ctx, func() error {
+		conn := m.migrationConn(ctx)
+		if !conn.Migrator().HasTable(&SeedRecord{}) {
+			if err := conn.Migrator().CreateTable(&SeedRecord{}); err != nil {
+				return fmt.Errorf("failed to create seeds table: %w", err)
+			}
+		}
+		var records []SeedRecord
+		if err := conn.Find(&records).Error; err != nil {
+			return fmt.Errorf("failed to read seeds: %w", err)
+		}
+
+		for _, seeder := range seeders {
+			module := seeder.ModuleName()
+			if len(modules) > 0 && !slices.Contains(modules, module) {
+				continue
+			}
+			for _, seed := range seeder.Seeds() {
+				if slices.ContainsFunc(records, func(r SeedRecord) bool { return r.Module == module && r.Name == seed.Name }) {
+					continue
+				}
+				if len(seed.Environments) > 0 && !slices.Contains(seed.Environments, env) {
+					continue
+				}
+
+				record, err := m.runSeed(ctx, module, env, seed)
+				if err != nil {
+					return err
+				}
+				ran = append(ran, record)
+			}
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// runSeed runs seed and records it in one transaction
+func (m *Migrator) runSeed(ctx context.Context, module, env string, seed Seed) (SeedRecord, error) {
+	m.log.Info("Running seed", String("module", module), String("seed", seed.Name))
+
+	start := time.Now()
+	var record SeedRecord
+	err := m.migrationConn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := seed.Run(ctx, tx); err != nil {
+			return fmt.Errorf("seed %s/%s failed: %w", module, seed.Name, err)
+		}
+		record = SeedRecord{
+			Module:      module,
+			Name:        seed.Name,
+			Environment: env,
+			RanAt:       time.Now().UTC(),
+			ExecutionMs: time.Since(start).Milliseconds(),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to record seed %s/%s: %w", module, seed.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return SeedRecord{}, err
+	}
+
+	m.log.Info("Seed ran",
+		String("module", module),
+		String("seed", seed.Name),
+		Int64("execution_ms", record.ExecutionMs))
+	return record, nil
+}
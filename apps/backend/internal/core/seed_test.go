@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type testSeeder struct {
+	module string
+	seeds  []Seed
+}
+
+func (s testSeeder) ModuleName() string { return s.module }
+func (s testSeeder) Seeds() []Seed      { return s.seeds }
+
+func seedNames(records []SeedRecord) []string {
+	names := make([]string, len(records))
+	for i, record := range records {
+		names[i] = record.Module + "/" + record.Name
+	}
+	return names
+}
+
+func TestSeed(t *testing.T) {
+	m := newSQLiteMigrator(t)
+	for _, name := range []string{"auth", "billing"} {
+		if err := m.schema.RegisterProvider(testProvider{name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs := make(map[string]int)
+	seed := func(name string, envs ...string) Seed {
+		return Seed{Name: name, Environments: envs, Run: func(context.Context, *gorm.DB) error {
+			runs[name]++
+			return nil
+		}}
+	}
+	m.seeders = []Seeder{
+		testSeeder{module: "billing", seeds: []Seed{seed("plans")}},
+		testSeeder{module: "auth", seeds: []Seed{seed("roles"), seed("demo_users", "development", "testing")}},
+	}
+
+	tests := []struct {
+		name    string
+		env     string
+		modules []string
+		want    []string
+	}{
+		{name: "one module", env: "production", modules: []string{"auth"}, want: []string{"auth/roles"}},
+		{name: "demo data left out of production", env: "production", want: []string{"billing/plans"}},
+		{name: "recorded seeds skipped", env: "development", want: []string{"auth/demo_users"}},
+		{name: "nothing pending", env: "testing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.config.App.Environment = tt.env
+			ran, err := m.Seed(context.Background(), tt.modules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := seedNames(ran); !slices.Equal(got, tt.want) {
+				t.Fatalf("Seed(%s, %v) ran %v, want %v", tt.env, tt.modules, got, tt.want)
+			}
+		})
+	}
+	for name, n := range runs {
+		if n != 1 {
+			t.Errorf("seed %s ran %d times, want once", name, n)
+		}
+	}
+
+	var records []SeedRecord
+	if err := m.db.Order("module, name").Find(&records).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got, want := seedNames(records), []string{"auth/demo_users", "auth/roles", "billing/plans"}; !slices.Equal(got, want) {
+		t.Fatalf("recorded %v, want %v", got, want)
+	}
+}
+
+func TestSeedRejects(t *testing.T) {
+	m := newSQLiteMigrator(t)
+	if err := m.schema.RegisterProvider(testProvider{name: "auth"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.config.App.Environment = "production"
+
+	if err := m.SeedDatabase("development", ""); err == nil {
+		t.Error("SeedDatabase() seeded development data into the production database")
+	}
+	if _, err := m.Seed(context.Background(), []string{"billing"}); err == nil {
+		t.Error("Seed() accepted an unregistered module")
+	}
+}
+
+func TestSeedRollsBackFailedSeed(t *testing.T) {
+	m := newSQLiteMigrator(t)
+	errSeed := errors.New("seed failed")
+	m.seeders = []Seeder{testSeeder{module: "auth", seeds: []Seed{{
+		Name: "roles",
+		Run: func(_ context.Context, tx *gorm.DB) error {
+			if err := tx.Exec("CREATE TABLE roles (name text)").Error; err != nil {
+				return err
+			}
+			return errSeed
+		},
+	}}}}
+
+	if _, err := m.Seed(context.Background(), nil); !errors.Is(err, errSeed) {
+		t.Fatalf("Seed() = %v, want %v", err, errSeed)
+	}
+	var recorded int64
+	if err := m.db.Model(&SeedRecord{}).Count(&recorded).Error; err != nil {
+		t.Fatal(err)
+	}
+	if recorded > 0 || m.db.Migrator().HasTable("roles") {
+		t.Fatal("failed seed was recorded or kept its changes")
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humagin"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/fx"
+)
+
+// HTTPServer wraps the gin engine and the Huma API that modules register routes on
+type HTTPServer struct {
+	Engine *gin.Engine
+	API    huma.API
+	server *http.Server
+	// metrics serves the metrics endpoint, nil when metrics are disabled
+	metrics *http.Server
+}
+
+func NewHTTPServer(cfg *Config, log Logger, registry *prometheus.Registry) *HTTPServer {
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	engine.Use(requestIDMiddleware)
+	engine.Use(readYourWritesMiddleware)
+	engine.Use(requestDeadlineMiddleware(cfg.Server.WriteTimeout))
+
+	engine.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "healthy",
+			"service": cfg.App.Name,
+		})
+	})
+
+	humaConfig := huma.DefaultConfig(cfg.App.Name, cfg.App.Version)
+	api := humagin.New(engine, humaConfig)
+
+	srv := &HTTPServer{
+		Engine: engine,
+		API:    api,
+		server: &http.Server{
+			Addr:         fmt.Sprintf(":%d", cfg.App.Port),
+			Handler:      engine,
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+		},
+	}
+	if cfg.Metrics.Enabled {
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Metrics.Path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		srv.metrics = &http.Server{
+			Addr:         cfg.Metrics.Addr,
+			Handler:      mux,
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+		}
+	}
+	return srv
+}
+
+func registerServerHooks(lc fx.Lifecycle, cfg *Config, log Logger, srv *HTTPServer) {
+	servers := []*http.Server{srv.server}
+	if srv.metrics != nil {
+		servers = append(servers, srv.metrics)
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			for _, server := range servers {
+				log.Info("Starting HTTP server", String("addr", server.Addr))
+				go func() {
+					if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						log.Error("HTTP server stopped unexpectedly", String("addr", server.Addr), Error(err))
+					}
+				}()
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			log.Info("Shutting down HTTP server")
+			ctx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+			defer cancel()
+
+			var errs []error
+			for _, server := range servers {
+				errs = append(errs, server.Shutdown(ctx))
+			}
+			return errors.Join(errs...)
+		},
+	})
+}
+
+// requestDeadlineMiddleware bounds the context of a request, and with it the
+// queries it makes, to the time left to write its response
+func requestDeadlineMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// readYourWritesMiddleware lets every request read its own writes even when
+// reads are served by lagging replicas
+func readYourWritesMiddleware(c *gin.Context) {
+	c.Request = c.Request.WithContext(WithReadYourWrites(c.Request.Context()))
+	c.Next()
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+func TestMetricsServedOffThePublicPort(t *testing.T) {
+	cfg := &Config{
+		App:     AppConfig{Name: "test", Version: "1.0.0", Port: 8080},
+		Server:  ServerConfig{WriteTimeout: time.Second},
+		Metrics: MetricsConfig{Path: "/metrics", Addr: ":9090"},
+	}
+	get := func(h http.Handler, path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	srv := NewHTTPServer(cfg, &zapLogger{logger: zap.NewNop()}, prometheus.NewRegistry())
+	if srv.metrics != nil {
+		t.Fatal("metrics served while disabled")
+	}
+
+	cfg.Metrics.Enabled = true
+	srv = NewHTTPServer(cfg, &zapLogger{logger: zap.NewNop()}, prometheus.NewRegistry())
+	if srv.metrics == nil || srv.metrics.Addr != ":9090" {
+		t.Fatalf("metrics server = %+v, want one listening on :9090", srv.metrics)
+	}
+	if code := get(srv.metrics.Handler, "/metrics"); code != http.StatusOK {
+		t.Errorf("metrics listener answered %d, want 200", code)
+	}
+	if code := get(srv.Engine, "/metrics"); code != http.StatusNotFound {
+		t.Errorf("public port answered %d on /metrics, want 404", code)
+	}
+}
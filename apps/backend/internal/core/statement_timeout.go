@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	statementCancelKey  = "core:statement_cancel"
+	statementContextKey = "core:statement_context"
+)
+
+type statementTimeoutKey struct{}
+
+// WithStatementTimeout returns ctx whose queries may run for at most d,
+// overriding the configured default. A d of zero or less disables the limit.
+func WithStatementTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, statementTimeoutKey{}, d)
+}
+
+// statementTimeout returns how long a query made with ctx may run: the
+// override carried by ctx or fallback, shortened to the deadline of ctx
+func statementTimeout(ctx context.Context, fallback time.Duration) time.Duration {
+	timeout := fallback
+	if d, ok := ctx.Value(statementTimeoutKey{}).(time.Duration); ok {
+		timeout = d
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); timeout <= 0 || remaining < timeout {
+			// An expired deadline still needs a positive timeout, as 0
+			// disables statement_timeout
+			timeout = max(remaining, time.Millisecond)
+		}
+	}
+	return timeout
+}
+
+// setLocalStatementTimeout bounds the statements of the Postgres transaction
+// tx on the server, so they are stopped even when the client cannot cancel them
+func (db *Database) setLocalStatementTimeout(ctx context.Context, tx *gorm.DB) error {
+	if db.dialect.Name != DBTypePostgres {
+		return nil
+	}
+	timeout := statementTimeout(ctx, db.statementTimeout)
+	if timeout <= 0 {
+		return nil
+	}
+	// SET does not accept bind parameters
+	sql := fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())
+	if err := tx.Exec(sql).Error; err != nil {
+		return fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+	return nil
+}
+
+// statementDeadline is a GORM plugin cancelling the context of statements
+// that outlive their timeout. Postgres transactions are bounded on the
+// server by setLocalStatementTimeout instead.
+type statementDeadline struct {
+	dialect  *Dialect
+	fallback time.Duration
+}
+
+func (p *statementDeadline) Name() string { return "core:statement_deadline" }
+
+// Initialize registers the plugin on every callback. Row results are scanned
+// after the callback chain ends, so their context is not canceled afterwards
+// but released by its own timer once the timeout passes.
+func (p *statementDeadline) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("core:deadline_before_create", p.before),
+		cb.Create().After("*").Register("core:deadline_after_create", p.after),
+		cb.Query().Before("*").Register("core:deadline_before_query", p.before),
+		cb.Query().After("*").Register("core:deadline_after_query", p.after),
+		cb.Update().Before("*").Register("core:deadline_before_update", p.before),
+		cb.Update().After("*").Register("core:deadline_after_update", p.after),
+		cb.Delete().Before("*").Register("core:deadline_before_delete", p.before),
+		cb.Delete().After("*").Register("core:deadline_after_delete", p.after),
+		cb.Raw().Before("*").Register("core:deadline_before_raw", p.before),
+		cb.Raw().After("*").Register("core:deadline_after_raw", p.after),
+		cb.Row().Before("*").Register("core:deadline_before_row", p.before),
+		cb.Row().After("*").Register("core:deadline_after_row", p.restore),
+	)
+}
+
+func (p *statementDeadline) before(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx && p.dialect.Name == DBTypePostgres {
+		return
+	}
+
+	timeout := statementTimeout(ctx, p.fallback)
+	if timeout <= 0 {
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= timeout {
+		// ctx already expires first and cancels the statement itself
+		return
+	}
+
+	bounded, cancel := context.WithTimeout(ctx, timeout)
+	db.InstanceSet(statementContextKey, ctx)
+	db.InstanceSet(statementCancelKey, cancel)
+	db.Statement.Context = bounded
+}
+
+func (p *statementDeadline) after(db *gorm.DB) {
+	if cancel, ok := db.InstanceGet(statementCancelKey); ok {
+		cancel.(context.CancelFunc)()
+	}
+	p.restore(db)
+}
+
+// restore gives the statement back the context it was started with
+func (p *statementDeadline) restore(db *gorm.DB) {
+	if ctx, ok := db.InstanceGet(statementContextKey); ok {
+		db.Statement.Context = ctx.(context.Context)
+	}
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	IsolationReadCommitted  = "read_committed"
+	IsolationRepeatableRead = "repeatable_read"
+	IsolationSerializable   = "serializable"
+
+	txRetryBaseDelay = 20 * time.Millisecond
+)
+
+type txContextKey struct{}
+
+// txState is the transaction carried by a context
+type txState struct {
+	tx     *gorm.DB
+	parent *txState
+
+	mu          sync.Mutex
+	afterCommit []func(ctx context.Context)
+}
+
+func (s *txState) addAfterCommit(fn func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.afterCommit = append(s.afterCommit, fn)
+}
+
+func (s *txState) takeAfterCommit() []func(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	callbacks := s.afterCommit
+	s.afterCommit = nil
+	return callbacks
+}
+
+// TxOptions configures a transaction started by the TxManager
+type TxOptions struct {
+	Isolation  sql.IsolationLevel
+	ReadOnly   bool
+	MaxRetries int
+}
+
+type TxOption func(*TxOptions)
+
+// WithIsolation sets the isolation level of a top-level transaction
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *TxOptions) { o.Isolation = level }
+}
+
+// WithReadOnly starts the transaction in read-only mode
+func WithReadOnly() TxOption {
+	return func(o *TxOptions) { o.ReadOnly = true }
+}
+
+// WithMaxRetries overrides how often a transaction is retried on serialization failures
+func WithMaxRetries(n int) TxOption {
+	return func(o *TxOptions) { o.MaxRetries = n }
+}
+
+// TxManager runs functions inside transactions propagated through context.Context,
+// so every repository call made with that context joins the same transaction.
+type TxManager struct {
+	db       *Database
+	log      Logger
+	defaults TxOptions
+}
+
+func NewTxManager(cfg *Config, db *Database, log Logger) *TxManager {
+	return &TxManager{
+		db:  db,
+		log: log,
+		defaults: TxOptions{
+			Isolation:  ParseIsolationLevel(cfg.Database.IsolationLevel),
+			MaxRetries: cfg.Database.TxMaxRetries,
+		},
+	}
+}
+
+// WithinTransaction runs fn in a transaction. When ctx already carries a
+// transaction, fn runs in a nested savepoint instead and only that savepoint
+// is rolled back on error. Top-level transactions are retried on
+// serialization failures and deadlocks.
+func (m *TxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	options := m.defaults
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if parent, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		var state *txState
+		err := parent.tx.WithContext(ctx).Transaction(func(sp *gorm.DB) error {
+			state = &txState{tx: sp, parent: parent}
+			if err := m.db.setLocalStatementTimeout(ctx, sp); err != nil {
+				return err
+			}
+			return fn(context.WithValue(ctx, txContextKey{}, state))
+		})
+		if err == nil && state != nil {
+			// Savepoint released: its callbacks now depend on the parent committing
+			for _, cb := range state.takeAfterCommit() {
+				parent.addAfterCommit(cb)
+			}
+		}
+		return err
+	}
+
+	txOptions := &sql.TxOptions{Isolation: options.Isolation, ReadOnly: options.ReadOnly}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var state *txState
+		err = m.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			state = &txState{tx: tx}
+			if err := m.db.setLocalStatementTimeout(ctx, tx); err != nil {
+				return err
+			}
+			return fn(context.WithValue(ctx, txContextKey{}, state))
+		}, txOptions)
+
+		if err == nil {
+			if !options.ReadOnly {
+				markWritten(ctx)
+			}
+			if state != nil {
+				for _, cb := range state.takeAfterCommit() {
+					cb(ctx)
+				}
+			}
+			return nil
+		}
+		if !IsRetryableTxError(err) || attempt >= options.MaxRetries {
+			return err
+		}
+
+		delay := retryDelay(txRetryBaseDelay, 0, attempt)
+		m.log.Warn("Retrying transaction",
+			Int("attempt", attempt+1),
+			String("delay", delay.String()),
+			Error(err))
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// TxFromContext returns the transaction carried by ctx, if any
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	state, ok := ctx.Value(txContextKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	return state.tx, true
+}
+
+// JoinTx returns ctx carrying the transaction of txCtx, unless ctx already
+// carries one. It lets code holding a transactional context lend it to
+// callers that pass their own context.
+func JoinTx(ctx, txCtx context.Context) context.Context {
+	if _, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		return ctx
+	}
+	state, ok := txCtx.Value(txContextKey{}).(*txState)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, txContextKey{}, state)
+}
+
+// AfterCommit runs fn once the transaction carried by ctx commits, and drops
+// it if the transaction (or the savepoint it was registered in) rolls back.
+// Without a transaction fn runs immediately.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	state, ok := ctx.Value(txContextKey{}).(*txState)
+	if !ok {
+		fn(ctx)
+		return
+	}
+	state.addAfterCommit(fn)
+}
+
+// IsRetryableTxError reports whether err is a serialization failure or deadlock
+func IsRetryableTxError(err error) bool {
+	err = TranslateDBError(err)
+	return errors.Is(err, ErrSerializationFailure) || errors.Is(err, ErrDeadlock)
+}
+
+// ParseIsolationLevel converts a configured isolation level to sql.IsolationLevel
+func ParseIsolationLevel(level string) sql.IsolationLevel {
+	switch level {
+	case IsolationReadCommitted:
+		return sql.LevelReadCommitted
+	case IsolationRepeatableRead:
+		return sql.LevelRepeatableRead
+	case IsolationSerializable:
+		return sql.LevelSerializable
+	default:
+		return sql.LevelDefault
+	}
+}
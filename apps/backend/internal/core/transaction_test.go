@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type txTestRow struct {
+	ID   int
+	Name string
+}
+
+func newTestTxManager(t *testing.T) (*TxManager, *Database) {
+	t.Helper()
+	conn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection opens its own in-memory database
+	sqlDB.SetMaxOpenConns(1)
+	if err := conn.AutoMigrate(&txTestRow{}); err != nil {
+		t.Fatal(err)
+	}
+
+	db := &Database{DB: conn, dialect: sqliteDialect}
+	return NewTxManager(&Config{}, db, &zapLogger{logger: zap.NewNop()}), db
+}
+
+func rowNames(t *testing.T, db *Database) []string {
+	t.Helper()
+	var names []string
+	if err := db.Model(&txTestRow{}).Order("id").Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestSavepointRollbackKeepsOuterTransaction(t *testing.T) {
+	tx, db := newTestTxManager(t)
+	errInner := errors.New("inner failed")
+
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if err := db.Conn(ctx).Create(&txTestRow{ID: 1, Name: "outer"}).Error; err != nil {
+			return err
+		}
+
+		err := tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			return db.Conn(ctx).Create(&txTestRow{ID: 2, Name: "released"}).Error
+		})
+		if err != nil {
+			return err
+		}
+
+		// A failed savepoint rolls back alone, nested savepoints with it
+		err = tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := db.Conn(ctx).Create(&txTestRow{ID: 3, Name: "rolled back"}).Error; err != nil {
+				return err
+			}
+			return tx.WithinTransaction(ctx, func(ctx context.Context) error {
+				if err := db.Conn(ctx).Create(&txTestRow{ID: 4, Name: "nested"}).Error; err != nil {
+					return err
+				}
+				return errInner
+			})
+		})
+		if !errors.Is(err, errInner) {
+			t.Fatalf("savepoint error = %v, want %v", err, errInner)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := rowNames(t, db), []string{"outer", "released"}; !slices.Equal(got, want) {
+		t.Fatalf("committed rows = %v, want %v", got, want)
+	}
+}
+
+func TestOuterRollbackDropsReleasedSavepoints(t *testing.T) {
+	tx, db := newTestTxManager(t)
+	errOuter := errors.New("outer failed")
+
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		err := tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			return db.Conn(ctx).Create(&txTestRow{ID: 1, Name: "released"}).Error
+		})
+		if err != nil {
+			return err
+		}
+		return errOuter
+	})
+	if !errors.Is(err, errOuter) {
+		t.Fatalf("WithinTransaction() = %v, want %v", err, errOuter)
+	}
+	if names := rowNames(t, db); len(names) != 0 {
+		t.Fatalf("rows %v survived the rollback", names)
+	}
+}
+
+func TestTransactionRetriesSerializationFailures(t *testing.T) {
+	tx, _ := newTestTxManager(t)
+	tx.defaults.MaxRetries = 2
+
+	attempts := 0
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return &pgconn.PgError{Code: sqlStateSerializationFailure}
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("WithinTransaction() = %v after %d attempts, want success after 2", err, attempts)
+	}
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"postgres serialization failure", &pgconn.PgError{Code: sqlStateSerializationFailure}, true},
+		{"postgres deadlock", &pgconn.PgError{Code: sqlStateDeadlockDetected}, true},
+		{"postgres unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"mysql deadlock", &mysql.MySQLError{Number: mysqlErrDeadlock}, true},
+		{"mysql lock wait timeout", &mysql.MySQLError{Number: 1205}, false},
+		{"wrapped", errors.Join(errors.New("commit"), &pgconn.PgError{Code: sqlStateDeadlockDetected}), true},
+		{"canceled", context.Canceled, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableTxError(tt.err); got != tt.want {
+				t.Fatalf("IsRetryableTxError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAfterCommitFollowsSavepoints(t *testing.T) {
+	tx, _ := newTestTxManager(t)
+	errInner := errors.New("inner failed")
+
+	var ran []string
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func(context.Context) { ran = append(ran, "outer") })
+
+		// A released savepoint hands its callbacks to the outer transaction
+		err := tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { ran = append(ran, "released") })
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// A failed savepoint drops them
+		err = tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { ran = append(ran, "rolled back") })
+			return errInner
+		})
+		if !errors.Is(err, errInner) {
+			t.Fatalf("savepoint error = %v, want %v", err, errInner)
+		}
+
+		if len(ran) != 0 {
+			t.Fatalf("callbacks ran before commit: %v", ran)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"outer", "released"}; !slices.Equal(ran, want) {
+		t.Fatalf("after commit ran %v, want %v", ran, want)
+	}
+
+	ran = nil
+	_ = tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func(context.Context) { ran = append(ran, "outer") })
+		return errInner
+	})
+	if len(ran) != 0 {
+		t.Fatalf("callbacks of a rolled back transaction ran: %v", ran)
+	}
+
+	AfterCommit(context.Background(), func(context.Context) { ran = append(ran, "immediate") })
+	if want := []string{"immediate"}; !slices.Equal(ran, want) {
+		t.Fatalf("AfterCommit() without a transaction ran %v, want %v", ran, want)
+	}
+}
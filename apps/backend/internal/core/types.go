@@ -8,11 +8,16 @@ import (
 )
 
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Cache    CacheConfig    `mapstructure:"cache"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
-	Server   ServerConfig   `mapstructure:"server"`
+	App       AppConfig       `mapstructure:"app"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Cache     CacheConfig     `mapstructure:"cache"`
+	Logger    LoggerConfig    `mapstructure:"logger"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Retention RetentionConfig `mapstructure:"retention"`
+	Outbox    OutboxConfig    `mapstructure:"outbox"`
+	Audit     AuditConfig     `mapstructure:"audit"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Seed      SeedConfig      `mapstructure:"seed"`
 }
 
 type AppConfig struct {
@@ -24,16 +29,40 @@ type AppConfig struct {
 }
 
 type DatabaseConfig struct {
-	Type            string        `mapstructure:"type"              validate:"required,oneof=postgres postgresql mysql sqlserver"`
-	Host            string        `mapstructure:"host"              validate:"required,hostname|ip"`
-	Port            int           `mapstructure:"port"              validate:"required,gt=0,lte=65535"`
-	User            string        `mapstructure:"user"`
-	Password        string        `mapstructure:"password"`
-	DBName          string        `mapstructure:"dbname"`
-	SSLMode         string        `mapstructure:"sslmode"           validate:"required,oneof=disable require verify-full verify-ca"`
-	MaxOpenConns    int           `mapstructure:"max_open_conns"    validate:"gt=0"`
-	MaxIdleConns    int           `mapstructure:"max_idle_conns"    validate:"gt=0"`
-	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" validate:"gt=0"`
+	Type                 string        `mapstructure:"type"                   validate:"required,oneof=postgres postgresql mysql sqlserver sqlite"`
+	Host                 string        `mapstructure:"host"                   validate:"required,hostname|ip"`
+	Port                 int           `mapstructure:"port"                   validate:"required,gt=0,lte=65535"`
+	User                 string        `mapstructure:"user"`
+	Password             string        `mapstructure:"password"`
+	DBName               string        `mapstructure:"dbname"`
+	SSLMode              string        `mapstructure:"sslmode"                validate:"required,oneof=disable require verify-full verify-ca"`
+	MaxOpenConns         int           `mapstructure:"max_open_conns"         validate:"gt=0"`
+	MaxIdleConns         int           `mapstructure:"max_idle_conns"         validate:"gt=0"`
+	ConnMaxLifetime      time.Duration `mapstructure:"conn_max_lifetime"      validate:"gt=0"`
+	IsolationLevel       string        `mapstructure:"isolation_level"        validate:"omitempty,oneof=read_committed repeatable_read serializable"`
+	TxMaxRetries         int           `mapstructure:"tx_max_retries"         validate:"gte=0"`
+	Replicas             []string      `mapstructure:"replicas"`
+	ReplicaPolicy        string        `mapstructure:"replica_policy"         validate:"omitempty,oneof=random round_robin"`
+	ConnectMaxAttempts   int           `mapstructure:"connect_max_attempts"   validate:"gte=0"`
+	ConnectRetryDelay    time.Duration `mapstructure:"connect_retry_delay"    validate:"gte=0"`
+	RetryMaxAttempts     int           `mapstructure:"retry_max_attempts"     validate:"gte=0"`
+	RetryBaseDelay       time.Duration `mapstructure:"retry_base_delay"       validate:"gte=0"`
+	RetryMaxDelay        time.Duration `mapstructure:"retry_max_delay"        validate:"gte=0"`
+	StatementTimeout     time.Duration `mapstructure:"statement_timeout"      validate:"gte=0"`
+	SlowQueryThreshold   time.Duration `mapstructure:"slow_query_threshold"   validate:"gte=0"`
+	LogQueryParams       bool          `mapstructure:"log_query_params"`
+	MigrationsDir        string        `mapstructure:"migrations_dir"`
+	LintLargeTableRows   int64         `mapstructure:"lint_large_table_rows"  validate:"gte=0"`
+	MigrateOnStart       bool          `mapstructure:"migrate_on_start"`
+	MigrationLockTimeout time.Duration `mapstructure:"migration_lock_timeout" validate:"gte=0"`
+}
+
+// MetricsConfig exposes Prometheus metrics on a listener of their own, kept
+// off the public port so they can be firewalled separately
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Addr    string `mapstructure:"addr"    validate:"required_if=Enabled true,omitempty,hostname_port"`
+	Path    string `mapstructure:"path"    validate:"startswith=/"`
 }
 
 type CacheConfig struct {
@@ -59,6 +88,46 @@ type ServerConfig struct {
 	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" validate:"gt=0"`
 }
 
+// RetentionConfig controls how long soft-deleted records are kept before purge
+type RetentionConfig struct {
+	PurgeEnabled  bool          `mapstructure:"purge_enabled"`
+	ArchiveTTL    time.Duration `mapstructure:"archive_ttl"    validate:"gt=0"`
+	PurgeInterval time.Duration `mapstructure:"purge_interval" validate:"gt=0"`
+}
+
+// OutboxConfig controls how outbox messages are relayed to external sinks
+type OutboxConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Topics         []string      `mapstructure:"topics"`
+	Sinks          []string      `mapstructure:"sinks"           validate:"dive,oneof=log webhook redis"`
+	PollInterval   time.Duration `mapstructure:"poll_interval"   validate:"gt=0"`
+	BatchSize      int           `mapstructure:"batch_size"      validate:"gt=0"`
+	MaxAttempts    int           `mapstructure:"max_attempts"    validate:"gt=0"`
+	BaseBackoff    time.Duration `mapstructure:"base_backoff"    validate:"gt=0"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"     validate:"gtefield=BaseBackoff"`
+	LeaseDuration  time.Duration `mapstructure:"lease_duration"  validate:"gt=0"`
+	DeliveredTTL   time.Duration `mapstructure:"delivered_ttl"   validate:"gte=0"`
+	WebhookURL     string        `mapstructure:"webhook_url"     validate:"omitempty,url"`
+	WebhookSecret  string        `mapstructure:"webhook_secret"`
+	WebhookTimeout time.Duration `mapstructure:"webhook_timeout" validate:"gt=0"`
+	RedisStream    string        `mapstructure:"redis_stream"`
+	RedisMaxLen    int64         `mapstructure:"redis_max_len"   validate:"gte=0"`
+}
+
+// SeedConfig configures the data loaded by module seeds
+type SeedConfig struct {
+	// DemoPassword is the password of the demo users, which are not seeded
+	// until it is set
+	DemoPassword string `mapstructure:"demo_password" validate:"omitempty,min=12"`
+}
+
+// AuditConfig controls which entity changes are recorded in the audit trail
+type AuditConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Topics are the entity event patterns recorded, none by default
+	Topics []string `mapstructure:"topics"`
+}
+
 type Logger interface {
 	Debug(msg string, fields ...zap.Field)
 	Info(msg string, fields ...zap.Field)
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// auditTag marks entity fields as `audit:"redact"` (value hidden) or `audit:"-"` (ignored)
+	auditTag    = "audit"
+	tagRedact   = "redact"
+	tagIgnore   = "-"
+	redactedVal = "[REDACTED]"
+)
+
+var schemaCache = &sync.Map{}
+
+// FieldChange holds the old and new value of a column. Old is omitted for
+// creations and New for deletions.
+type FieldChange struct {
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+// diff compares two snapshots of the same entity column by column, naming
+// columns with namer. Either snapshot may be nil; auto-updated timestamps are
+// left out.
+func diff(namer schema.Namer, before, after any) (map[string]FieldChange, error) {
+	model := after
+	if model == nil {
+		model = before
+	}
+	if model == nil {
+		return nil, nil
+	}
+
+	sch, err := schema.Parse(model, schemaCache, namer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	changes := make(map[string]FieldChange)
+	for _, field := range sch.Fields {
+		tag := field.Tag.Get(auditTag)
+		if field.DBName == "" || field.AutoUpdateTime > 0 || tag == tagIgnore {
+			continue
+		}
+
+		oldVal, oldZero := fieldValue(field, before)
+		newVal, newZero := fieldValue(field, after)
+
+		switch {
+		case before == nil && newZero, after == nil && oldZero:
+			continue
+		case before != nil && after != nil && reflect.DeepEqual(oldVal, newVal):
+			continue
+		}
+
+		if tag == tagRedact {
+			oldVal, newVal = redact(before, oldVal), redact(after, newVal)
+		}
+		changes[field.DBName] = FieldChange{Old: oldVal, New: newVal}
+	}
+
+	return changes, nil
+}
+
+func fieldValue(field *schema.Field, snapshot any) (any, bool) {
+	if snapshot == nil {
+		return nil, true
+	}
+	return field.ValueOf(context.Background(), reflect.Indirect(reflect.ValueOf(snapshot)))
+}
+
+func redact(snapshot, value any) any {
+	if snapshot == nil {
+		return value
+	}
+	return redactedVal
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+type diffUser struct {
+	ID        int
+	Name      string
+	Password  string `audit:"redact"`
+	Internal  string `audit:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func TestDiff(t *testing.T) {
+	created := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	alice := &diffUser{ID: 1, Name: "alice", Password: "secret", Internal: "x", CreatedAt: created, UpdatedAt: created}
+
+	renamed := *alice
+	renamed.Name, renamed.Internal, renamed.UpdatedAt = "alicia", "y", created.Add(time.Hour)
+
+	rekeyed := *alice
+	rekeyed.Password = "hunter2"
+
+	tests := []struct {
+		name          string
+		before, after any
+		want          map[string]FieldChange
+	}{
+		{
+			name:  "create",
+			after: alice,
+			want: map[string]FieldChange{
+				"id":         {New: 1},
+				"name":       {New: "alice"},
+				"password":   {New: redactedVal},
+				"created_at": {New: created},
+			},
+		},
+		{
+			name:   "update skips ignored and auto-updated columns",
+			before: alice,
+			after:  &renamed,
+			want:   map[string]FieldChange{"name": {Old: "alice", New: "alicia"}},
+		},
+		{
+			name:   "update redacts both values",
+			before: alice,
+			after:  &rekeyed,
+			want:   map[string]FieldChange{"password": {Old: redactedVal, New: redactedVal}},
+		},
+		{
+			name:   "unchanged",
+			before: alice,
+			after:  alice,
+			want:   map[string]FieldChange{},
+		},
+		{
+			name:   "delete",
+			before: alice,
+			want: map[string]FieldChange{
+				"id":         {Old: 1},
+				"name":       {Old: "alice"},
+				"password":   {Old: redactedVal},
+				"created_at": {Old: created},
+			},
+		},
+		{
+			name: "no snapshots",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diff(schema.NamingStrategy{}, tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/johna210/go-next-flutter/internal/shared/model"
+)
+
+// AuditLog records a single change to an entity: who made it, on behalf of
+// which tenant, and the before/after values of every changed column.
+type AuditLog struct {
+	model.BaseModel `gorm:"embedded"`
+
+	ActorID    *uuid.UUID `gorm:"type:uuid;index"`
+	TenantID   string     `gorm:"not null;default:'';index"`
+	EntityType string     `gorm:"not null;index:idx_audit_logs_entity,priority:1"`
+	EntityID   uuid.UUID  `gorm:"type:uuid;not null;index:idx_audit_logs_entity,priority:2"`
+	Action     string     `gorm:"type:varchar(16);not null"`
+	Changes    []byte     `gorm:"type:jsonb;not null"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/johna210/go-next-flutter/internal/modules/audit/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+	collectionquery "github.com/johna210/go-next-flutter/pkg/collection_query"
+)
+
+type ListAuditLogsRequest struct {
+	collectionquery.QueryParams
+}
+
+type AuditLogData struct {
+	ID         string          `json:"id"`
+	ActorID    *string         `json:"actor_id"`
+	TenantID   string          `json:"tenant_id,omitempty"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Action     string          `json:"action"`
+	Changes    json.RawMessage `json:"changes"`
+	CreatedAt  *time.Time      `json:"created_at"`
+}
+
+type ListAuditLogsResponse struct {
+	Body struct {
+		Data           []AuditLogData `json:"data" doc:"Audit log entries"`
+		Total          int64          `json:"total" doc:"Total number of matching entries"`
+		TotalEstimated bool           `json:"total_estimated" doc:"Whether total is an estimate"`
+		Page           int            `json:"page" doc:"Current page"`
+		PageSize       int            `json:"page_size" doc:"Page size used"`
+		TotalPages     int            `json:"total_pages" doc:"Total number of pages"`
+		HasNext        bool           `json:"has_next" doc:"Whether a next page exists"`
+		HasPrev        bool           `json:"has_prev" doc:"Whether a previous page exists"`
+	}
+}
+
+func ToListAuditLogsResponse(result repository.PaginatedResult[entity.AuditLog]) *ListAuditLogsResponse {
+	resp := &ListAuditLogsResponse{}
+	resp.Body.Data = make([]AuditLogData, len(result.Data))
+	for i, log := range result.Data {
+		data := AuditLogData{
+			ID:         log.ID.String(),
+			TenantID:   log.TenantID,
+			EntityType: log.EntityType,
+			EntityID:   log.EntityID.String(),
+			Action:     log.Action,
+			Changes:    json.RawMessage(log.Changes),
+			CreatedAt:  log.CreatedAt,
+		}
+		if log.ActorID != nil {
+			actorID := log.ActorID.String()
+			data.ActorID = &actorID
+		}
+		resp.Body.Data[i] = data
+	}
+	resp.Body.Total = result.Total
+	resp.Body.TotalEstimated = result.TotalEstimated
+	resp.Body.Page = result.Page
+	resp.Body.PageSize = result.PageSize
+	resp.Body.TotalPages = result.TotalPages
+	resp.Body.HasNext = result.HasNext
+	resp.Body.HasPrev = result.HasPrev
+	return resp
+}
@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/audit/domain/entity"
+)
+
+// EntityProvider implements core.EntityProvider for audit module
+type EntityProvider struct{}
+
+// NewEntityProvider creates the entity provider
+func NewEntityProvider() core.EntityProvider {
+	return &EntityProvider{}
+}
+
+// Entities returns all domain entities for audit module
+func (p *EntityProvider) Entities() []interface{} {
+	return []interface{}{
+		&entity.AuditLog{},
+	}
+}
+
+// ModuleName returns the module identifier
+func (p *EntityProvider) ModuleName() string {
+	return "audit"
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/johna210/go-next-flutter/internal/modules/audit/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/modules/audit/dto"
+	"github.com/johna210/go-next-flutter/internal/shared/httperror"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+	collectionquery "github.com/johna210/go-next-flutter/pkg/collection_query"
+)
+
+// filterableColumns are the audit log columns callers may filter, group and
+// sort by. Changes is returned but never queried, so redacted values cannot be
+// probed through JSON path filters.
+var filterableColumns = map[string]bool{
+	"id":          true,
+	"created_at":  true,
+	"actor_id":    true,
+	"tenant_id":   true,
+	"entity_type": true,
+	"entity_id":   true,
+	"action":      true,
+}
+
+type AuditHandler struct {
+	logs repository.GenericRepository[entity.AuditLog]
+}
+
+func NewAuditHandler(logs repository.GenericRepository[entity.AuditLog]) *AuditHandler {
+	return &AuditHandler{logs: logs}
+}
+
+func (h *AuditHandler) ListAuditLogs(
+	ctx context.Context,
+	input *dto.ListAuditLogsRequest,
+) (*dto.ListAuditLogsResponse, error) {
+	query, err := input.ToCollectionQuery()
+	if err != nil {
+		return nil, huma.Error400BadRequest("Invalid collection query", err)
+	}
+	if err := checkColumns(query); err != nil {
+		return nil, huma.Error400BadRequest("Invalid collection query", err)
+	}
+
+	// Newest entries first unless the caller asks otherwise
+	if len(query.OrderBy) == 0 {
+		desc := collectionquery.Descending
+		query.OrderBy = []collectionquery.Order{{Column: "created_at", Direction: &desc}}
+	}
+
+	// The audit trail grows without bound, so unfiltered listings use the
+	// planner's estimate instead of counting every row
+	result, err := h.logs.FindAll(ctx, query, repository.WithCountStrategy(repository.CountEstimated))
+	if err != nil {
+		return nil, httperror.FromRepository(err, "Failed to list audit logs")
+	}
+
+	return dto.ToListAuditLogsResponse(result), nil
+}
+
+// checkColumns rejects queries naming columns outside filterableColumns. Only
+// changes may additionally be selected; relations are not exposed.
+func checkColumns(query collectionquery.CollectionQuery) error {
+	for _, col := range query.Select {
+		if col != "changes" && !filterableColumns[col] {
+			return fmt.Errorf("column %q cannot be selected", col)
+		}
+	}
+	for _, group := range slices.Concat(query.Where, query.Having) {
+		for _, clause := range group {
+			if !filterableColumns[clause.Column] {
+				return fmt.Errorf("column %q cannot be filtered", clause.Column)
+			}
+		}
+	}
+	for _, col := range query.GroupBy {
+		if !filterableColumns[col] {
+			return fmt.Errorf("column %q cannot be grouped", col)
+		}
+	}
+	for _, order := range query.OrderBy {
+		if !filterableColumns[order.Column] {
+			return fmt.Errorf("column %q cannot be sorted", order.Column)
+		}
+	}
+	if len(query.Includes) > 0 || len(query.IncludeAndSelect) > 0 {
+		return fmt.Errorf("audit logs have no relations to include")
+	}
+	return nil
+}
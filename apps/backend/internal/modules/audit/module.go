@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/audit/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/modules/audit/handler"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+)
+
+var Module = fx.Module("audit",
+	// Provide entity provider, private so it does not clash with other modules
+	fx.Provide(
+		fx.Private,
+		fx.Annotate(
+			NewEntityProvider,
+			fx.As(new(core.EntityProvider)),
+		),
+	),
+
+	// Recorder, repositories and handlers
+	fx.Provide(
+		NewRecorder,
+		repository.NewBaseRepository[entity.AuditLog],
+		handler.NewAuditHandler,
+	),
+
+	// Auto-register with schema manager
+	fx.Invoke(func(sm *core.SchemaManager, provider core.EntityProvider) {
+		if err := sm.RegisterProvider(provider); err != nil {
+			panic(err)
+		}
+	}),
+
+	fx.Invoke(registerRecorder),
+)
+
+// Routes registers the audit log routes. The API does not authenticate
+// requests yet, so Module leaves them out until it does; they are meant for
+// holders of auth.PermissionAuditRead.
+var Routes = fx.Invoke(registerAuditRoutes)
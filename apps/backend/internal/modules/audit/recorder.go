@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/audit/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+)
+
+// Recorder writes an AuditLog for every entity change, inside the transaction
+// that made the change, so history can never disagree with the data.
+type Recorder struct {
+	db *core.Database
+}
+
+func NewRecorder(db *core.Database) *Recorder {
+	return &Recorder{db: db}
+}
+
+// Record stores the audit entry for change using the transaction carried by ctx
+func (r *Recorder) Record(ctx context.Context, change repository.EntityChange) error {
+	before, after := change.Snapshots()
+	changes, err := diff(r.db.NamingStrategy, before, after)
+	if err != nil {
+		return fmt.Errorf("failed to diff %s: %w", change.EntityName(), err)
+	}
+	if len(changes) == 0 && change.EntityAction() == repository.EntityUpdated {
+		return nil
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s changes: %w", change.EntityName(), err)
+	}
+
+	log := &entity.AuditLog{
+		EntityType: change.EntityName(),
+		EntityID:   change.EntityID(),
+		Action:     string(change.EntityAction()),
+		Changes:    data,
+	}
+	if actor, ok := core.ActorFromContext(ctx); ok {
+		log.ActorID = &actor.ID
+		log.TenantID = actor.TenantID
+	}
+
+	if err := r.db.Conn(ctx).Create(log).Error; err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// handle is subscribed in-transaction to the topics of audit.topics
+func (r *Recorder) handle(ctx context.Context, event core.Event) error {
+	change, ok := event.(repository.EntityChange)
+	if !ok {
+		return nil
+	}
+	return r.Record(ctx, change)
+}
+
+// registerRecorder subscribes the recorder to the audited topics only, so
+// repositories skip reading the previous state of entities nobody audits
+func registerRecorder(cfg *core.Config, bus core.EventBus, r *Recorder) {
+	if !cfg.Audit.Enabled {
+		return
+	}
+	for _, pattern := range distinctPatterns(cfg.Audit.Topics) {
+		bus.Subscribe(pattern, r.handle, core.WithDelivery(core.DeliverInTx))
+	}
+}
+
+// distinctPatterns drops the patterns matched by another one, so no event is
+// recorded twice
+func distinctPatterns(patterns []string) []string {
+	patterns = slices.Compact(slices.Sorted(slices.Values(patterns)))
+	return slices.DeleteFunc(slices.Clone(patterns), func(pattern string) bool {
+		return slices.ContainsFunc(patterns, func(other string) bool {
+			return other != pattern && core.TopicMatches(other, pattern)
+		})
+	})
+}
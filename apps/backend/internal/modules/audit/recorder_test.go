@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+)
+
+// patternBus records the patterns subscribed to
+type patternBus struct {
+	core.EventBus
+	patterns []string
+}
+
+func (b *patternBus) Subscribe(pattern string, _ core.EventHandler, _ ...core.SubscribeOption) {
+	b.patterns = append(b.patterns, pattern)
+}
+
+func TestRegisterRecorder(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		topics  []string
+		want    []string
+	}{
+		{name: "disabled", topics: []string{"users.*"}},
+		{name: "no topics", enabled: true},
+		{name: "distinct", enabled: true, topics: []string{"users.*", "roles.deleted"}, want: []string{"roles.deleted", "users.*"}},
+		{name: "covered", enabled: true, topics: []string{"users.updated", "users.*", "users.*"}, want: []string{"users.*"}},
+		{name: "everything", enabled: true, topics: []string{"roles.deleted", "*"}, want: []string{"*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &core.Config{Audit: core.AuditConfig{Enabled: tt.enabled, Topics: tt.topics}}
+			bus := &patternBus{}
+			registerRecorder(cfg, bus, NewRecorder(nil))
+			if !slices.Equal(bus.patterns, tt.want) {
+				t.Fatalf("subscribed to %v, want %v", bus.patterns, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorderIgnoresOtherEvents(t *testing.T) {
+	// Recording would fail without a database
+	if err := NewRecorder(nil).handle(context.Background(), otherEvent{}); err != nil {
+		t.Fatalf("handle() = %v, want events without entity changes ignored", err)
+	}
+}
+
+type otherEvent struct{}
+
+func (otherEvent) Topic() string { return "users.signed_in" }
@@ -0,0 +1,21 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/audit/handler"
+)
+
+func registerAuditRoutes(srv *core.HTTPServer, h *handler.AuditHandler) {
+	huma.Register(srv.API, huma.Operation{
+		OperationID: "list-audit-logs",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/admin/audit-logs",
+		Summary:     "List audit logs",
+		Description: "Retrieves a paginated, filterable history of entity changes",
+		Tags:        []string{"Admin"},
+	}, h.ListAuditLogs)
+}
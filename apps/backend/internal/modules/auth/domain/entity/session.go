@@ -13,7 +13,7 @@ type Session struct {
 
 	UserID       uuid.UUID `gorm:"type:uuid;not null;index"`
 	JWTID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
-	RefreshToken string    `gorm:"not null"`
+	RefreshToken string    `gorm:"not null" json:"-" audit:"redact"`
 	ExpiresAt    time.Time `gorm:"not null"`
 	Revoked      bool      `gorm:"default:false"`
 	IPAddress    string
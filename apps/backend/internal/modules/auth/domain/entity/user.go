@@ -7,11 +7,12 @@ type User struct {
 
 	Username     string `gorm:"uniqueIndex;not null"`
 	Email        string `gorm:"uniqueIndex;not null"`
-	PasswordHash string `gorm:"not null"`
+	PasswordHash string `gorm:"not null" json:"-" audit:"redact"`
 	IsActive     bool   `gorm:"default:false"`
 
-	Profile UserProfile `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	Roles   []UserRole  `gorm:"foreignKey:UserID"`
+	Profile  UserProfile `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Sessions []Session   `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Roles    []UserRole  `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
 }
 
 func (User) TableName() string {
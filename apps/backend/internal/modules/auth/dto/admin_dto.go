@@ -0,0 +1,95 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/johna210/go-next-flutter/internal/modules/auth/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+	collectionquery "github.com/johna210/go-next-flutter/pkg/collection_query"
+)
+
+type ListArchivedUsersRequest struct {
+	collectionquery.QueryParams
+}
+
+type RestoreUserRequest struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"User unique identifier" example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+type RestoreUsersRequest struct {
+	Body struct {
+		IDs []uuid.UUID `json:"ids" minItems:"1" maxItems:"500" doc:"IDs of archived users to restore"`
+	}
+}
+
+type PurgeArchivedRequest struct {
+	OlderThan string `query:"older_than" doc:"Purge records archived longer than this duration ago, at least the retention TTL (the default)" example:"720h"`
+}
+
+type ArchivedUserData struct {
+	ID        string     `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	IsActive  bool       `json:"is_active"`
+	CreatedAt *time.Time `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type ListArchivedUsersResponse struct {
+	Body struct {
+		Data           []ArchivedUserData `json:"data" doc:"Archived users"`
+		Total          int64              `json:"total" doc:"Total number of archived users"`
+		TotalEstimated bool               `json:"total_estimated" doc:"Whether total is an estimate"`
+		Page           int                `json:"page" doc:"Current page"`
+		PageSize       int                `json:"page_size" doc:"Page size used"`
+		TotalPages     int                `json:"total_pages" doc:"Total number of pages"`
+		HasNext        bool               `json:"has_next" doc:"Whether a next page exists"`
+		HasPrev        bool               `json:"has_prev" doc:"Whether a previous page exists"`
+	}
+}
+
+type AffectedResponse struct {
+	Body struct {
+		Affected int64 `json:"affected" doc:"Number of records affected"`
+	}
+}
+
+type MessageResponse struct {
+	Body struct {
+		Message string `json:"message" doc:"Response message"`
+	}
+}
+
+func ToListArchivedUsersResponse(result repository.PaginatedResult[entity.User]) *ListArchivedUsersResponse {
+	resp := &ListArchivedUsersResponse{}
+	resp.Body.Data = make([]ArchivedUserData, len(result.Data))
+	for i, user := range result.Data {
+		data := ArchivedUserData{
+			ID:        user.ID.String(),
+			Username:  user.Username,
+			Email:     user.Email,
+			IsActive:  user.IsActive,
+			CreatedAt: user.CreatedAt,
+		}
+		if user.DeletedAt != nil && user.DeletedAt.Valid {
+			data.DeletedAt = &user.DeletedAt.Time
+		}
+		resp.Body.Data[i] = data
+	}
+	resp.Body.Total = result.Total
+	resp.Body.TotalEstimated = result.TotalEstimated
+	resp.Body.Page = result.Page
+	resp.Body.PageSize = result.PageSize
+	resp.Body.TotalPages = result.TotalPages
+	resp.Body.HasNext = result.HasNext
+	resp.Body.HasPrev = result.HasPrev
+	return resp
+}
+
+func ToAffectedResponse(affected int64) *AffectedResponse {
+	resp := &AffectedResponse{}
+	resp.Body.Affected = affected
+	return resp
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/auth/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/modules/auth/dto"
+	"github.com/johna210/go-next-flutter/internal/shared/httperror"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+)
+
+type AdminHandler struct {
+	users     repository.GenericRepository[entity.User]
+	retention core.RetentionConfig
+}
+
+func NewAdminHandler(cfg *core.Config, users repository.GenericRepository[entity.User]) *AdminHandler {
+	return &AdminHandler{
+		users:     users,
+		retention: cfg.Retention,
+	}
+}
+
+func (h *AdminHandler) ListArchivedUsers(
+	ctx context.Context,
+	input *dto.ListArchivedUsersRequest,
+) (*dto.ListArchivedUsersResponse, error) {
+	query, err := input.ToCollectionQuery()
+	if err != nil {
+		return nil, huma.Error400BadRequest("Invalid collection query", err)
+	}
+
+	result, err := h.users.FindAllArchived(ctx, query)
+	if err != nil {
+		return nil, httperror.FromRepository(err, "Failed to list archived users")
+	}
+
+	return dto.ToListArchivedUsersResponse(result), nil
+}
+
+func (h *AdminHandler) RestoreUser(ctx context.Context, input *dto.RestoreUserRequest) (*dto.MessageResponse, error) {
+	if err := h.users.Restore(ctx, input.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, huma.Error404NotFound("Archived user not found")
+		}
+		return nil, httperror.FromRepository(err, "Failed to restore user")
+	}
+
+	resp := &dto.MessageResponse{}
+	resp.Body.Message = "User restored successfully"
+	return resp, nil
+}
+
+func (h *AdminHandler) RestoreUsers(ctx context.Context, input *dto.RestoreUsersRequest) (*dto.AffectedResponse, error) {
+	restored, err := h.users.RestoreMany(ctx, input.Body.IDs)
+	if err != nil {
+		return nil, httperror.FromRepository(err, "Failed to restore users")
+	}
+
+	return dto.ToAffectedResponse(restored), nil
+}
+
+func (h *AdminHandler) PurgeArchivedUsers(
+	ctx context.Context,
+	input *dto.PurgeArchivedRequest,
+) (*dto.AffectedResponse, error) {
+	olderThan := h.retention.ArchiveTTL
+	if input.OlderThan != "" {
+		parsed, err := time.ParseDuration(input.OlderThan)
+		if err != nil {
+			return nil, huma.Error400BadRequest("Invalid older_than duration")
+		}
+		// Purging is permanent, so it never reaches records the retention
+		// TTL still keeps
+		if parsed < h.retention.ArchiveTTL {
+			return nil, huma.Error400BadRequest("older_than must be at least " + h.retention.ArchiveTTL.String())
+		}
+		olderThan = parsed
+	}
+
+	purged, err := h.users.PurgeArchived(ctx, olderThan)
+	if err != nil {
+		return nil, httperror.FromRepository(err, "Failed to purge archived users")
+	}
+
+	return dto.ToAffectedResponse(purged), nil
+}
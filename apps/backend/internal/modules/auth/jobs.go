@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/auth/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+)
+
+// registerPurgeJob schedules the removal of users and sessions that have been
+// archived for longer than the configured retention TTL.
+func registerPurgeJob(
+	cfg *core.Config,
+	log core.Logger,
+	scheduler *core.Scheduler,
+	users repository.GenericRepository[entity.User],
+	sessions repository.GenericRepository[entity.Session],
+) {
+	if !cfg.Retention.PurgeEnabled {
+		return
+	}
+
+	ttl := cfg.Retention.ArchiveTTL
+	scheduler.Every("auth.purge-archived", cfg.Retention.PurgeInterval, func(ctx context.Context) error {
+		purgedUsers, err := users.PurgeArchived(ctx, ttl)
+		if err != nil {
+			return err
+		}
+
+		purgedSessions, err := sessions.PurgeArchived(ctx, ttl)
+		if err != nil {
+			return err
+		}
+
+		log.Info("Purged archived auth records",
+			core.Int64("users", purgedUsers),
+			core.Int64("sessions", purgedSessions))
+		return nil
+	})
+}
@@ -4,6 +4,10 @@ import (
 	"go.uber.org/fx"
 
 	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/auth/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/modules/auth/handler"
+	"github.com/johna210/go-next-flutter/internal/modules/auth/service"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
 )
 
 var Module = fx.Module("auth",
@@ -15,10 +19,35 @@ var Module = fx.Module("auth",
 		),
 	),
 
+	// Default roles and demo users
+	fx.Provide(
+		fx.Annotate(
+			NewSeeder,
+			fx.As(new(core.Seeder)),
+			fx.ResultTags(`group:"seeders"`),
+		),
+	),
+
+	// Repositories, services and handlers
+	fx.Provide(
+		repository.NewBaseRepository[entity.User],
+		repository.NewBaseRepository[entity.UserProfile],
+		repository.NewBaseRepository[entity.UserRole],
+		repository.NewBaseRepository[entity.Session],
+		service.NewUserService,
+		handler.NewAdminHandler,
+	),
+
 	// Auto-register with schema manager
 	fx.Invoke(func(sm *core.SchemaManager, provider core.EntityProvider) {
 		if err := sm.RegisterProvider(provider); err != nil {
 			panic(err)
 		}
 	}),
+
+	fx.Invoke(registerPurgeJob),
 )
+
+// AdminRoutes registers the user administration routes. The API does not
+// authenticate requests yet, so Module leaves them out until it does.
+var AdminRoutes = fx.Invoke(registerAdminRoutes)
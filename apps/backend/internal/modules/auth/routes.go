@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/auth/handler"
+)
+
+func registerAdminRoutes(srv *core.HTTPServer, h *handler.AdminHandler) {
+	api := srv.API
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-archived-users",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/admin/users/archived",
+		Summary:     "List archived users",
+		Description: "Retrieves a paginated list of soft deleted users",
+		Tags:        []string{"Admin"},
+	}, h.ListArchivedUsers)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-user",
+		Method:      http.MethodPost,
+		Path:        "/api/v1/admin/users/{id}/restore",
+		Summary:     "Restore user",
+		Description: "Restores a soft deleted user together with its profile and sessions",
+		Tags:        []string{"Admin"},
+	}, h.RestoreUser)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-users",
+		Method:      http.MethodPost,
+		Path:        "/api/v1/admin/users/restore",
+		Summary:     "Restore users",
+		Description: "Restores multiple soft deleted users",
+		Tags:        []string{"Admin"},
+	}, h.RestoreUsers)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "purge-archived-users",
+		Method:      http.MethodDelete,
+		Path:        "/api/v1/admin/users/archived",
+		Summary:     "Purge archived users",
+		Description: "Permanently deletes users archived longer than the given duration",
+		Tags:        []string{"Admin"},
+	}, h.PurgeArchivedUsers)
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/auth/domain/entity"
+)
+
+// Permissions granted by the default roles
+const (
+	PermissionUsersRead    = "users.read"
+	PermissionUsersRestore = "users.restore"
+	PermissionUsersPurge   = "users.purge"
+	PermissionAuditRead    = "audit.read"
+)
+
+var defaultPermissions = []struct {
+	name        string
+	description string
+}{
+	{PermissionUsersRead, "List users, archived ones included"},
+	{PermissionUsersRestore, "Restore archived users"},
+	{PermissionUsersPurge, "Purge archived users for good"},
+	{PermissionAuditRead, "Read the audit log"},
+}
+
+var defaultRoles = []struct {
+	name        string
+	description string
+	permissions []string
+}{
+	{"admin", "Manages users and reads the audit log", []string{
+		PermissionUsersRead, PermissionUsersRestore, PermissionUsersPurge, PermissionAuditRead,
+	}},
+	{"support", "Helps users and restores archived accounts", []string{PermissionUsersRead, PermissionUsersRestore}},
+	{"user", "Signed up user", nil},
+}
+
+var demoUsers = []struct {
+	username  string
+	email     string
+	firstName string
+	role      string
+}{
+	{"admin", "admin@example.com", "Admin", "admin"},
+	{"support", "support@example.com", "Support", "support"},
+	{"demo", "demo@example.com", "Demo", "user"},
+}
+
+// Seeder implements core.Seeder for auth module
+type Seeder struct {
+	demoPassword string
+}
+
+// NewSeeder creates the seeder
+func NewSeeder(cfg *core.Config) core.Seeder {
+	return &Seeder{demoPassword: cfg.Seed.DemoPassword}
+}
+
+// ModuleName returns the module identifier
+func (s *Seeder) ModuleName() string {
+	return "auth"
+}
+
+// Seeds returns the default roles, and the demo users outside production once
+// seed.demo_password is set
+func (s *Seeder) Seeds() []core.Seed {
+	seeds := []core.Seed{{Name: "default_roles", Run: seedDefaultRoles}}
+	if s.demoPassword != "" {
+		seeds = append(seeds, core.Seed{
+			Name:         "demo_users",
+			Environments: []string{"development", "testing", "local"},
+			Run:          s.seedDemoUsers,
+		})
+	}
+	return seeds
+}
+
+// seedDefaultRoles creates the default permissions and the roles granting them
+func seedDefaultRoles(_ context.Context, tx *gorm.DB) error {
+	permissions := make(map[string]uuid.UUID, len(defaultPermissions))
+	for _, p := range defaultPermissions {
+		permission := entity.Permission{Name: p.name, Description: p.description}
+		if err := tx.Where(&entity.Permission{Name: p.name}).FirstOrCreate(&permission).Error; err != nil {
+			return fmt.Errorf("failed to seed permission %s: %w", p.name, err)
+		}
+		permissions[p.name] = permission.ID
+	}
+
+	for _, r := range defaultRoles {
+		role := entity.Role{Name: r.name, Description: r.description}
+		if err := tx.Where(&entity.Role{Name: r.name}).FirstOrCreate(&role).Error; err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", r.name, err)
+		}
+		for _, name := range r.permissions {
+			grant := entity.RolePermission{RoleID: role.ID, PermissionID: permissions[name]}
+			if err := tx.Where(&grant).FirstOrCreate(&grant).Error; err != nil {
+				return fmt.Errorf("failed to grant %s to role %s: %w", name, r.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// seedDemoUsers creates an active user with a profile for every default role
+func (s *Seeder) seedDemoUsers(_ context.Context, tx *gorm.DB) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(s.demoPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range demoUsers {
+		var role entity.Role
+		if err := tx.Where(&entity.Role{Name: u.role}).First(&role).Error; err != nil {
+			return fmt.Errorf("failed to find role %s, seed default_roles first: %w", u.role, err)
+		}
+
+		user := entity.User{Username: u.username, Email: u.email, PasswordHash: string(hash), IsActive: true}
+		if err := tx.Omit(clause.Associations).Where(&entity.User{Username: u.username}).FirstOrCreate(&user).Error; err != nil {
+			return fmt.Errorf("failed to seed user %s: %w", u.username, err)
+		}
+		profile := entity.UserProfile{UserID: user.ID, FirstName: u.firstName, LastName: "User"}
+		if err := tx.Omit(clause.Associations).Where(&entity.UserProfile{UserID: user.ID}).FirstOrCreate(&profile).Error; err != nil {
+			return fmt.Errorf("failed to seed profile of %s: %w", u.username, err)
+		}
+		userRole := entity.UserRole{UserID: user.ID, RoleID: role.ID}
+		if err := tx.Where(&userRole).FirstOrCreate(&userRole).Error; err != nil {
+			return fmt.Errorf("failed to assign role %s to %s: %w", u.role, u.username, err)
+		}
+	}
+	return nil
+}
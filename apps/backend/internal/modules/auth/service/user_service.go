@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/auth/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+)
+
+type UserService struct {
+	tx        *core.TxManager
+	users     repository.GenericRepository[entity.User]
+	profiles  repository.GenericRepository[entity.UserProfile]
+	userRoles repository.GenericRepository[entity.UserRole]
+}
+
+func NewUserService(
+	tx *core.TxManager,
+	users repository.GenericRepository[entity.User],
+	profiles repository.GenericRepository[entity.UserProfile],
+	userRoles repository.GenericRepository[entity.UserRole],
+) *UserService {
+	return &UserService{
+		tx:        tx,
+		users:     users,
+		profiles:  profiles,
+		userRoles: userRoles,
+	}
+}
+
+// Register creates a user, its profile and its role assignments atomically
+func (s *UserService) Register(
+	ctx context.Context,
+	user *entity.User,
+	profile *entity.UserProfile,
+	roleIDs []uuid.UUID,
+) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.users.Create(ctx, user); err != nil {
+			return err
+		}
+
+		profile.UserID = user.ID
+		if err := s.profiles.Create(ctx, profile); err != nil {
+			return err
+		}
+
+		if len(roleIDs) == 0 {
+			return nil
+		}
+
+		userRoles := make([]*entity.UserRole, len(roleIDs))
+		for i, roleID := range roleIDs {
+			userRoles[i] = &entity.UserRole{UserID: user.ID, RoleID: roleID}
+		}
+		return s.userRoles.BulkCreate(ctx, userRoles)
+	})
+}
@@ -3,9 +3,13 @@ package modules
 import (
 	"go.uber.org/fx"
 
+	"github.com/johna210/go-next-flutter/internal/modules/audit"
 	"github.com/johna210/go-next-flutter/internal/modules/auth"
+	"github.com/johna210/go-next-flutter/internal/modules/outbox"
 )
 
 var Modules = fx.Options(
 	auth.Module,
+	audit.Module,
+	outbox.Module,
 )
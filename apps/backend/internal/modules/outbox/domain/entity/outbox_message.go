@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/johna210/go-next-flutter/internal/shared/model"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxPending   OutboxStatus = "pending"
+	OutboxDelivered OutboxStatus = "delivered"
+	OutboxDead      OutboxStatus = "dead"
+)
+
+// OutboxMessage is an event waiting to be relayed to external sinks. It is
+// written in the same transaction as the change that produced it.
+type OutboxMessage struct {
+	model.BaseModel `gorm:"embedded"`
+
+	Topic         string       `gorm:"not null;index"`
+	AggregateType string       `gorm:"not null;default:''"`
+	AggregateID   *uuid.UUID   `gorm:"type:uuid"`
+	Payload       []byte       `gorm:"type:jsonb;not null"`
+	Status        OutboxStatus `gorm:"type:varchar(16);not null;default:'pending';index:idx_outbox_messages_due,priority:1"`
+	Attempts      int          `gorm:"not null;default:0"`
+	NextAttemptAt time.Time    `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_outbox_messages_due,priority:2"`
+	LeasedUntil   *time.Time
+	LastError     string
+	DeliveredAt   *time.Time `gorm:"index"`
+
+	// Deliveries lists the sinks the message already reached, loaded by the relay
+	Deliveries []OutboxDelivery `gorm:"-"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
+
+// OutboxDelivery records that a message reached one sink, so retries of the
+// message skip the sinks that already have it
+type OutboxDelivery struct {
+	MessageID   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Sink        string    `gorm:"type:varchar(64);primaryKey"`
+	DeliveredAt time.Time `gorm:"not null"`
+}
+
+func (OutboxDelivery) TableName() string {
+	return "outbox_deliveries"
+}
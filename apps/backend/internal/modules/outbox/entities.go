@@ -0,0 +1,27 @@
+package outbox
+
+import (
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/outbox/domain/entity"
+)
+
+// EntityProvider implements core.EntityProvider for outbox module
+type EntityProvider struct{}
+
+// NewEntityProvider creates the entity provider
+func NewEntityProvider() core.EntityProvider {
+	return &EntityProvider{}
+}
+
+// Entities returns all domain entities for outbox module
+func (p *EntityProvider) Entities() []interface{} {
+	return []interface{}{
+		&entity.OutboxMessage{},
+		&entity.OutboxDelivery{},
+	}
+}
+
+// ModuleName returns the module identifier
+func (p *EntityProvider) ModuleName() string {
+	return "outbox"
+}
@@ -0,0 +1,40 @@
+package outbox
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+)
+
+var Module = fx.Module("outbox",
+	// Provide entity provider, private so it does not clash with other modules
+	fx.Provide(
+		fx.Private,
+		fx.Annotate(
+			NewEntityProvider,
+			fx.As(new(core.EntityProvider)),
+		),
+	),
+
+	// Writer, sinks and relay
+	fx.Provide(
+		NewWriter,
+		fx.Annotate(
+			newConfiguredSinks,
+			fx.ResultTags(`group:"outbox_sinks,flatten"`),
+		),
+		fx.Annotate(
+			NewRelay,
+			fx.ParamTags(``, ``, ``, ``, `group:"outbox_sinks"`),
+		),
+	),
+
+	// Auto-register with schema manager
+	fx.Invoke(func(sm *core.SchemaManager, provider core.EntityProvider) {
+		if err := sm.RegisterProvider(provider); err != nil {
+			panic(err)
+		}
+	}),
+
+	fx.Invoke(registerWriter, registerRelay),
+)
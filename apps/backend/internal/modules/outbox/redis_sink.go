@@ -0,0 +1,47 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/johna210/go-next-flutter/internal/modules/outbox/domain/entity"
+)
+
+// RedisStreamSink appends each message to a Redis stream
+type RedisStreamSink struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+func NewRedisStreamSink(client *redis.Client, stream string, maxLen int64) *RedisStreamSink {
+	return &RedisStreamSink{client: client, stream: stream, maxLen: maxLen}
+}
+
+func (s *RedisStreamSink) Name() string { return SinkRedis }
+
+func (s *RedisStreamSink) Deliver(ctx context.Context, msg *entity.OutboxMessage) error {
+	aggregateID := ""
+	if msg.AggregateID != nil {
+		aggregateID = msg.AggregateID.String()
+	}
+
+	err := s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: s.maxLen > 0,
+		Values: map[string]any{
+			"id":             msg.ID.String(),
+			"topic":          msg.Topic,
+			"aggregate_type": msg.AggregateType,
+			"aggregate_id":   aggregateID,
+			"payload":        string(msg.Payload),
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to add message to stream %s: %w", s.stream, err)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/outbox/domain/entity"
+)
+
+const (
+	// lastErrorLimit caps the stored delivery error so one bad sink cannot bloat rows
+	lastErrorLimit = 1024
+
+	// purgeBatchSize bounds how many delivered messages are purged per transaction
+	purgeBatchSize = 500
+)
+
+// Relay delivers pending outbox messages to the configured sinks. A batch is
+// claimed with SELECT ... FOR UPDATE SKIP LOCKED and leased to this relay by
+// setting leased_until, then the claim commits and the messages are delivered
+// outside any transaction. Several app instances can thus relay concurrently
+// without delivering the same message twice at once, and the messages of a
+// relay that dies mid-batch become due again once its lease expires. The lease
+// must outlast the delivery of a whole batch.
+type Relay struct {
+	cfg   core.OutboxConfig
+	db    *core.Database
+	tx    *core.TxManager
+	log   core.Logger
+	sinks []Sink
+}
+
+func NewRelay(cfg *core.Config, db *core.Database, tx *core.TxManager, log core.Logger, sinks []Sink) *Relay {
+	return &Relay{cfg: cfg.Outbox, db: db, tx: tx, log: log, sinks: sinks}
+}
+
+// Run relays batches until no due messages are left or ctx is cancelled
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.RelayBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if n < r.cfg.BatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// RelayBatch claims one batch of due messages, delivers them and records the
+// outcome. It returns the number of messages processed.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	messages, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range messages {
+		if err := r.relay(ctx, &messages[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(messages), nil
+}
+
+// claim leases a batch of due messages to this relay and loads the sinks
+// each of them already reached
+func (r *Relay) claim(ctx context.Context) ([]entity.OutboxMessage, error) {
+	var messages []entity.OutboxMessage
+	err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		err := r.db.Conn(ctx).
+			Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+			Where("status = ? AND next_attempt_at <= ?", entity.OutboxPending, now).
+			Where("leased_until IS NULL OR leased_until <= ?", now).
+			Order("next_attempt_at").
+			Limit(r.cfg.BatchSize).
+			Find(&messages).Error
+		if err != nil {
+			return fmt.Errorf("failed to claim outbox messages: %w", err)
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(messages))
+		for i := range messages {
+			ids[i] = messages[i].ID
+		}
+		err = r.db.Conn(ctx).Model(&entity.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Update("leased_until", now.Add(r.cfg.LeaseDuration)).Error
+		if err != nil {
+			return fmt.Errorf("failed to lease outbox messages: %w", err)
+		}
+
+		var deliveries []entity.OutboxDelivery
+		if err := r.db.Conn(ctx).Where("message_id IN ?", ids).Find(&deliveries).Error; err != nil {
+			return fmt.Errorf("failed to load outbox deliveries: %w", err)
+		}
+		byMessage := make(map[uuid.UUID][]entity.OutboxDelivery, len(messages))
+		for _, delivery := range deliveries {
+			byMessage[delivery.MessageID] = append(byMessage[delivery.MessageID], delivery)
+		}
+		for i := range messages {
+			messages[i].Deliveries = byMessage[messages[i].ID]
+		}
+		return nil
+	})
+	return messages, err
+}
+
+// relay delivers msg to every sink it has not reached yet, then records the
+// sinks that took it and releases its lease
+func (r *Relay) relay(ctx context.Context, msg *entity.OutboxMessage) error {
+	delivered, err := r.deliver(ctx, msg)
+
+	now := time.Now()
+	updates := map[string]any{"attempts": msg.Attempts + 1, "leased_until": nil}
+	if err != nil {
+		updates["last_error"] = truncate(err.Error(), lastErrorLimit)
+
+		if msg.Attempts+1 >= r.cfg.MaxAttempts {
+			updates["status"] = entity.OutboxDead
+			r.log.Error("Outbox message dead-lettered",
+				core.String("id", msg.ID.String()),
+				core.String("topic", msg.Topic),
+				core.Int("attempts", msg.Attempts+1),
+				core.Error(err))
+		} else {
+			updates["next_attempt_at"] = now.Add(r.backoff(msg.Attempts + 1))
+			r.log.Warn("Outbox delivery failed",
+				core.String("id", msg.ID.String()),
+				core.String("topic", msg.Topic),
+				core.Int("attempts", msg.Attempts+1),
+				core.Error(err))
+		}
+	} else {
+		updates["status"] = entity.OutboxDelivered
+		updates["delivered_at"] = now
+		updates["last_error"] = ""
+	}
+
+	err = r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if len(delivered) > 0 {
+			err := r.db.Conn(ctx).
+				Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&delivered).Error
+			if err != nil {
+				return err
+			}
+		}
+		return r.db.Conn(ctx).Model(&entity.OutboxMessage{}).
+			Where("id = ?", msg.ID).
+			Updates(updates).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// deliver hands msg to the sinks it has not reached yet and returns the
+// deliveries that succeeded along with the errors of those that failed
+func (r *Relay) deliver(ctx context.Context, msg *entity.OutboxMessage) ([]entity.OutboxDelivery, error) {
+	reached := make(map[string]bool, len(msg.Deliveries))
+	for _, delivery := range msg.Deliveries {
+		reached[delivery.Sink] = true
+	}
+
+	var (
+		delivered []entity.OutboxDelivery
+		errs      []error
+	)
+	for _, sink := range r.sinks {
+		if reached[sink.Name()] {
+			continue
+		}
+		if err := sink.Deliver(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+		delivered = append(delivered, entity.OutboxDelivery{
+			MessageID:   msg.ID,
+			Sink:        sink.Name(),
+			DeliveredAt: time.Now(),
+		})
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// Purge deletes delivered messages, and the record of their deliveries, once
+// they are older than the configured TTL. It returns the number of messages
+// deleted.
+func (r *Relay) Purge(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-r.cfg.DeliveredTTL)
+
+	var purged int64
+	for {
+		var batch int64
+		err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			var ids []uuid.UUID
+			err := r.db.Conn(ctx).Model(&entity.OutboxMessage{}).
+				Where("status = ? AND delivered_at < ?", entity.OutboxDelivered, cutoff).
+				Limit(purgeBatchSize).
+				Pluck("id", &ids).Error
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+
+			if err := r.db.Conn(ctx).Where("message_id IN ?", ids).Delete(&entity.OutboxDelivery{}).Error; err != nil {
+				return err
+			}
+			result := r.db.Conn(ctx).Unscoped().Where("id IN ?", ids).Delete(&entity.OutboxMessage{})
+			batch = result.RowsAffected
+			return result.Error
+		})
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge delivered outbox messages: %w", err)
+		}
+
+		purged += batch
+		if batch < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// backoff doubles the base delay for every failed attempt, up to MaxBackoff
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < r.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.cfg.MaxBackoff)
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit]
+}
+
+func registerRelay(cfg *core.Config, log core.Logger, scheduler *core.Scheduler, relay *Relay) {
+	if !cfg.Outbox.Enabled {
+		return
+	}
+	if len(relay.sinks) == 0 {
+		log.Warn("Outbox is enabled without sinks, messages will stay pending")
+		return
+	}
+	scheduler.Every("outbox.relay", cfg.Outbox.PollInterval, relay.Run)
+
+	if cfg.Outbox.DeliveredTTL <= 0 {
+		return
+	}
+	scheduler.Every("outbox.purge-delivered", cfg.Retention.PurgeInterval, func(ctx context.Context) error {
+		purged, err := relay.Purge(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("Purged delivered outbox messages", core.Int64("messages", purged))
+		return nil
+	})
+}
@@ -0,0 +1,199 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/outbox/domain/entity"
+)
+
+// flakySink fails its first failures deliveries and counts every call
+type flakySink struct {
+	name     string
+	failures int
+	calls    int
+}
+
+func (s *flakySink) Name() string { return s.name }
+
+func (s *flakySink) Deliver(ctx context.Context, msg *entity.OutboxMessage) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func newTestRelay(t *testing.T, sinks ...Sink) *Relay {
+	t.Helper()
+	cfg := &core.Config{
+		Logger: core.LoggerConfig{Level: "error", Encoding: "console", OutputPaths: []string{"stderr"}},
+		Database: core.DatabaseConfig{
+			Type:         core.DBTypeSQLite,
+			DBName:       ":memory:",
+			MaxOpenConns: 1,
+			MaxIdleConns: 1,
+		},
+		Outbox: core.OutboxConfig{
+			BatchSize:     10,
+			MaxAttempts:   5,
+			BaseBackoff:   time.Millisecond,
+			MaxBackoff:    time.Millisecond,
+			LeaseDuration: time.Minute,
+			DeliveredTTL:  time.Hour,
+		},
+	}
+	log, err := core.NewLogger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := core.NewDatabase(cfg, log, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	entities := []any{&entity.OutboxMessage{}, &entity.OutboxDelivery{}}
+	if err := db.Dialect().AdaptSchema(db.DB, entities...); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(entities...); err != nil {
+		t.Fatal(err)
+	}
+	return NewRelay(cfg, db, core.NewTxManager(cfg, db, log), log, sinks)
+}
+
+func enqueue(t *testing.T, relay *Relay) *entity.OutboxMessage {
+	t.Helper()
+	msg := &entity.OutboxMessage{
+		Topic:         "users.created",
+		Payload:       []byte(`{}`),
+		Status:        entity.OutboxPending,
+		NextAttemptAt: time.Now().Add(-time.Second),
+	}
+	if err := relay.db.Create(msg).Error; err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func reload(t *testing.T, relay *Relay, msg *entity.OutboxMessage) *entity.OutboxMessage {
+	t.Helper()
+	var got entity.OutboxMessage
+	if err := relay.db.First(&got, "id = ?", msg.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &got
+}
+
+func TestRelaySkipsSinksThatAlreadyDelivered(t *testing.T) {
+	healthy := &flakySink{name: "healthy"}
+	flaky := &flakySink{name: "flaky", failures: 1}
+	relay := newTestRelay(t, healthy, flaky)
+	msg := enqueue(t, relay)
+	ctx := context.Background()
+
+	if _, err := relay.RelayBatch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got := reload(t, relay, msg)
+	if got.Status != entity.OutboxPending || got.Attempts != 1 || got.LeasedUntil != nil {
+		t.Fatalf("after failed delivery: status %s, attempts %d, leased until %v", got.Status, got.Attempts, got.LeasedUntil)
+	}
+
+	// Wait out the backoff, then retry: only the flaky sink is called again
+	time.Sleep(5 * time.Millisecond)
+	if _, err := relay.RelayBatch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got = reload(t, relay, msg)
+	if got.Status != entity.OutboxDelivered || got.DeliveredAt == nil {
+		t.Fatalf("after retry: status %s, delivered at %v", got.Status, got.DeliveredAt)
+	}
+	if healthy.calls != 1 || flaky.calls != 2 {
+		t.Fatalf("sink calls: healthy %d, flaky %d, want 1 and 2", healthy.calls, flaky.calls)
+	}
+}
+
+func TestRelaySkipsLeasedMessages(t *testing.T) {
+	sink := &flakySink{name: "log"}
+	relay := newTestRelay(t, sink)
+	msg := enqueue(t, relay)
+	ctx := context.Background()
+
+	// A lease held by another relay hides the message until it expires
+	lease := time.Now().Add(time.Minute)
+	if err := relay.db.Model(msg).Update("leased_until", lease).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n, err := relay.RelayBatch(ctx); err != nil || n != 0 {
+		t.Fatalf("RelayBatch() = %d, %v, want 0 messages", n, err)
+	}
+
+	expired := time.Now().Add(-time.Second)
+	if err := relay.db.Model(msg).Update("leased_until", expired).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n, err := relay.RelayBatch(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayBatch() = %d, %v, want 1 message", n, err)
+	}
+	if sink.calls != 1 {
+		t.Fatalf("sink calls = %d, want 1", sink.calls)
+	}
+}
+
+func TestPurgeDeletesOldDeliveredMessages(t *testing.T) {
+	relay := newTestRelay(t, &flakySink{name: "log"})
+	ctx := context.Background()
+
+	old, recent, pending := enqueue(t, relay), enqueue(t, relay), enqueue(t, relay)
+	for msg, deliveredAt := range map[*entity.OutboxMessage]time.Time{
+		old:    time.Now().Add(-2 * time.Hour),
+		recent: time.Now(),
+	} {
+		err := relay.db.Model(msg).Updates(map[string]any{
+			"status":       entity.OutboxDelivered,
+			"delivered_at": deliveredAt,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		delivery := entity.OutboxDelivery{MessageID: msg.ID, Sink: "log", DeliveredAt: deliveredAt}
+		if err := relay.db.Create(&delivery).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	purged, err := relay.Purge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Fatalf("Purge() = %d, want 1", purged)
+	}
+
+	var remaining []entity.OutboxMessage
+	if err := relay.db.Unscoped().Order("created_at").Find(&remaining).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range remaining {
+		if msg.ID == old.ID {
+			t.Fatal("the old delivered message was not purged")
+		}
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("%d messages remain, want the recent and pending ones (%s, %s)", len(remaining), recent.ID, pending.ID)
+	}
+
+	var deliveries int64
+	if err := relay.db.Model(&entity.OutboxDelivery{}).Where("message_id = ?", old.ID).Count(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if deliveries != 0 {
+		t.Fatalf("%d deliveries of the purged message remain", deliveries)
+	}
+}
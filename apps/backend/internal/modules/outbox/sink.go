@@ -0,0 +1,78 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/outbox/domain/entity"
+)
+
+const (
+	SinkLog     = "log"
+	SinkWebhook = "webhook"
+	SinkRedis   = "redis"
+)
+
+// Sink delivers outbox messages to an external system. Delivery is
+// at-least-once: the relay records every sink a message reached and skips it
+// when retrying the others, but a message may be handed to a sink again if
+// the relay crashed before recording the result.
+//
+// Modules can add their own sinks to the "outbox_sinks" fx group.
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, msg *entity.OutboxMessage) error
+}
+
+// LogSink writes messages to the application log
+type LogSink struct {
+	log core.Logger
+}
+
+func NewLogSink(log core.Logger) *LogSink {
+	return &LogSink{log: log}
+}
+
+func (s *LogSink) Name() string { return SinkLog }
+
+func (s *LogSink) Deliver(ctx context.Context, msg *entity.OutboxMessage) error {
+	s.log.Info("Outbox message",
+		core.String("id", msg.ID.String()),
+		core.String("topic", msg.Topic),
+		core.String("payload", string(msg.Payload)))
+	return nil
+}
+
+// newConfiguredSinks builds the built-in sinks enabled in outbox.sinks
+func newConfiguredSinks(lc fx.Lifecycle, cfg *core.Config, log core.Logger) ([]Sink, error) {
+	if !cfg.Outbox.Enabled {
+		return nil, nil
+	}
+
+	sinks := make([]Sink, 0, len(cfg.Outbox.Sinks))
+	for _, name := range cfg.Outbox.Sinks {
+		switch name {
+		case SinkLog:
+			sinks = append(sinks, NewLogSink(log))
+		case SinkWebhook:
+			client := &http.Client{Timeout: cfg.Outbox.WebhookTimeout}
+			sinks = append(sinks, NewWebhookSink(client, cfg.Outbox.WebhookURL, cfg.Outbox.WebhookSecret))
+		case SinkRedis:
+			client := redis.NewClient(&redis.Options{
+				Addr:     cfg.GetAddr(),
+				Password: cfg.Cache.Password,
+				DB:       cfg.Cache.DB,
+			})
+			lc.Append(fx.StopHook(client.Close))
+			sinks = append(sinks, NewRedisStreamSink(client, cfg.Outbox.RedisStream, cfg.Outbox.RedisMaxLen))
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	return sinks, nil
+}
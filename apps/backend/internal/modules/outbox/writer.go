@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	"github.com/johna210/go-next-flutter/internal/modules/outbox/domain/entity"
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+)
+
+// entityPayload is the outbox representation of a repository.EntityChange
+type entityPayload struct {
+	Entity     string                  `json:"entity"`
+	ID         uuid.UUID               `json:"id"`
+	Action     repository.EntityAction `json:"action"`
+	Before     any                     `json:"before,omitempty"`
+	After      any                     `json:"after,omitempty"`
+	OccurredAt time.Time               `json:"occurred_at"`
+}
+
+// Writer stores events in the outbox as part of the publisher's transaction,
+// so a message exists if and only if the change that produced it committed.
+type Writer struct {
+	db     *core.Database
+	topics []string
+}
+
+func NewWriter(cfg *core.Config, db *core.Database) *Writer {
+	return &Writer{db: db, topics: cfg.Outbox.Topics}
+}
+
+// Write inserts event into the outbox using the transaction carried by ctx
+func (w *Writer) Write(ctx context.Context, event core.Event) error {
+	msg, err := newMessage(event)
+	if err != nil {
+		return err
+	}
+
+	if err := w.db.Conn(ctx).Create(msg).Error; err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+	return nil
+}
+
+// handle is subscribed in-transaction to every event on the bus
+func (w *Writer) handle(ctx context.Context, event core.Event) error {
+	topic := event.Topic()
+	if !slices.ContainsFunc(w.topics, func(pattern string) bool {
+		return core.TopicMatches(pattern, topic)
+	}) {
+		return nil
+	}
+	return w.Write(ctx, event)
+}
+
+func newMessage(event core.Event) (*entity.OutboxMessage, error) {
+	msg := &entity.OutboxMessage{
+		Topic:         event.Topic(),
+		Status:        entity.OutboxPending,
+		NextAttemptAt: time.Now(),
+	}
+
+	var payload any = event
+	if change, ok := event.(repository.EntityChange); ok {
+		id := change.EntityID()
+		before, after := change.Snapshots()
+
+		msg.AggregateType = change.EntityName()
+		msg.AggregateID = &id
+		payload = entityPayload{
+			Entity:     change.EntityName(),
+			ID:         id,
+			Action:     change.EntityAction(),
+			Before:     before,
+			After:      after,
+			OccurredAt: time.Now().UTC(),
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s outbox payload: %w", msg.Topic, err)
+	}
+	msg.Payload = data
+	return msg, nil
+}
+
+func registerWriter(cfg *core.Config, bus core.EventBus, w *Writer) {
+	if !cfg.Outbox.Enabled {
+		return
+	}
+	bus.Subscribe("*", w.handle, core.WithDelivery(core.DeliverInTx))
+}
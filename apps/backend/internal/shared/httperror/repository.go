@@ -0,0 +1,67 @@
+package httperror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/johna210/go-next-flutter/internal/shared/repository"
+	collectionquery "github.com/johna210/go-next-flutter/pkg/collection_query"
+)
+
+// retryAfterSeconds is suggested to clients when a request lost a concurrency race
+const retryAfterSeconds = "1"
+
+// FromRepository converts a repository error into a Huma error response:
+// filters the database cannot run become 400, conflicts 409, invalid
+// references or values 422, concurrency failures and canceled queries 503
+// and timed out queries 504. Any other error becomes a 500 with fallback as its message,
+// so driver details never reach the client.
+func FromRepository(err error, fallback string) error {
+	var dbErr *repository.DBError
+	errors.As(err, &dbErr)
+
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return huma.Error404NotFound("Record not found")
+
+	case errors.Is(err, collectionquery.ErrUnsupportedOperator):
+		return huma.Error400BadRequest(err.Error())
+
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return huma.Error409Conflict("Record already exists", fieldErrors(dbErr)...)
+
+	case errors.Is(err, repository.ErrForeignKeyViolation),
+		errors.Is(err, repository.ErrNotNullViolation),
+		errors.Is(err, repository.ErrCheckViolation):
+		return huma.Error422UnprocessableEntity("Invalid field value", fieldErrors(dbErr)...)
+
+	case errors.Is(err, repository.ErrSerializationFailure),
+		errors.Is(err, repository.ErrDeadlock),
+		errors.Is(err, repository.ErrLockNotAvailable):
+		return huma.ErrorWithHeaders(
+			huma.Error503ServiceUnavailable("Record is busy, please retry"),
+			http.Header{"Retry-After": {retryAfterSeconds}},
+		)
+
+	case errors.Is(err, repository.ErrQueryTimeout):
+		return huma.Error504GatewayTimeout("Request timed out")
+
+	case errors.Is(err, repository.ErrQueryCanceled):
+		return huma.Error503ServiceUnavailable("Request was canceled")
+	}
+
+	return huma.Error500InternalServerError(fallback)
+}
+
+// fieldErrors describes the offending column of dbErr, if known
+func fieldErrors(dbErr *repository.DBError) []error {
+	if dbErr == nil || dbErr.Column == "" {
+		return nil
+	}
+	return []error{&huma.ErrorDetail{
+		Location: "body." + dbErr.Column,
+		Message:  dbErr.Kind.Error(),
+	}}
+}
@@ -3,7 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
-	"math"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -12,219 +12,450 @@ import (
 	collectionquery "github.com/johna210/go-next-flutter/pkg/collection_query"
 )
 
-var (
-	ErrNotFound      = errors.New("record not found")
-	ErrAlreadyExists = errors.New("record already exists")
-)
+// purgeBatchSize bounds how many archived rows are purged per transaction
+const purgeBatchSize = 500
 
 type BaseRepository[T any] struct {
 	db     *core.Database
 	logger core.Logger
+	tx     *core.TxManager
+	events core.EventBus
+
+	// txCtx is set on repositories handed out by Transaction
+	txCtx context.Context
 }
 
-func NewBaseRepository[T any](db *core.Database, logger core.Logger) GenericRepository[T] {
+func NewBaseRepository[T any](
+	db *core.Database,
+	logger core.Logger,
+	tx *core.TxManager,
+	events core.EventBus,
+) GenericRepository[T] {
 	return &BaseRepository[T]{
 		db:     db,
 		logger: logger,
+		tx:     tx,
+		events: events,
 	}
 }
 
 func (r *BaseRepository[T]) Create(ctx context.Context, entity *T) error {
-	if err := r.db.WithContext(ctx).Create(entity).Error; err != nil {
-		r.logger.Error("Failed to create entity", core.Error(err))
-		return err
+	err := r.tx.WithinTransaction(r.context(ctx), func(ctx context.Context) error {
+		if err := r.db.Conn(ctx).Create(entity).Error; err != nil {
+			return err
+		}
+		return r.publish(ctx, EntityCreated, nil, entity, false)
+	})
+	if err != nil {
+		return r.fail("Failed to create entity", err)
 	}
 	return nil
 }
 
 func (r *BaseRepository[T]) BulkCreate(ctx context.Context, entities []*T) error {
-	if err := r.db.WithContext(ctx).CreateInBatches(entities, 100).Error; err != nil {
-		r.logger.Error("Failed to bulk create entities", core.Error(err))
-		return err
+	err := r.tx.WithinTransaction(r.context(ctx), func(ctx context.Context) error {
+		if err := r.db.Conn(ctx).CreateInBatches(entities, 100).Error; err != nil {
+			return err
+		}
+		for _, entity := range entities {
+			if err := r.publish(ctx, EntityCreated, nil, entity, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return r.fail("Failed to bulk create entities", err)
 	}
 	return nil
 }
 
-func (r *BaseRepository[T]) GetByID(ctx context.Context, id uuid.UUID) (*T, error) {
+func (r *BaseRepository[T]) GetByID(ctx context.Context, id uuid.UUID, opts ...FindOption) (*T, error) {
+	options := newFindOptions(opts)
+	lock, err := lockClause(r.context(ctx), r.db.DB, options.lock)
+	if err != nil {
+		return nil, err
+	}
+
 	var entity T
-	if err := r.db.WithContext(ctx).First(&entity, "id = ?", id).Error; err != nil {
+	err = r.read(ctx, "get by id", func(ctx context.Context) error {
+		query := r.conn(ctx)
+		if lock != nil {
+			query = query.Clauses(*lock)
+		}
+		return query.First(&entity, "id = ?", id).Error
+	})
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotFound
 		}
-		r.logger.Error("Failed to get entity by ID", core.Error(err))
-		return nil, err
+		return nil, r.fail("Failed to get entity by ID", err)
 	}
 	return &entity, nil
 }
 
 func (r *BaseRepository[T]) Update(ctx context.Context, entity *T) error {
-	if err := r.db.WithContext(ctx).Save(entity).Error; err != nil {
-		r.logger.Error("Failed to update entity", core.Error(err))
-		return err
+	err := r.tx.WithinTransaction(r.context(ctx), func(ctx context.Context) error {
+		var before *T
+		if r.observed(EntityUpdated) {
+			sch, err := parseSchema(r.db.DB, new(T))
+			if err != nil {
+				return err
+			}
+			if before, err = r.find(ctx, EntityUpdated, entityID(sch, entity), false); err != nil {
+				return err
+			}
+		}
+
+		if err := r.db.Conn(ctx).Save(entity).Error; err != nil {
+			return err
+		}
+		return r.publish(ctx, EntityUpdated, before, entity, false)
+	})
+	if err != nil {
+		return r.fail("Failed to update entity", err)
 	}
 	return nil
 }
 
 func (r *BaseRepository[T]) Delete(ctx context.Context, id uuid.UUID) error {
-	if err := r.db.WithContext(ctx).Delete(new(T), "id = ?", id).Error; err != nil {
-		r.logger.Error("Failed to delete entity", core.Error(err))
-		return err
+	err := r.tx.WithinTransaction(r.context(ctx), func(ctx context.Context) error {
+		tx := r.db.Conn(ctx)
+		sch, err := parseSchema(tx, new(T))
+		if err != nil {
+			return err
+		}
+
+		before, err := r.find(ctx, EntityDeleted, id, false)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(new(T), "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := cascadeSoftDelete(tx, sch, []uuid.UUID{id}); err != nil {
+			return err
+		}
+		return r.publish(ctx, EntityDeleted, before, nil, false)
+	})
+	if err != nil {
+		return r.fail("Failed to delete entity", err)
 	}
 	return nil
 }
 
 func (r *BaseRepository[T]) HardDelete(ctx context.Context, id uuid.UUID) error {
-	if err := r.db.WithContext(ctx).Unscoped().Delete(new(T), "id = ?", id).Error; err != nil {
-		r.logger.Error("Failed to hard delete entity", core.Error(err))
-		return err
+	err := r.tx.WithinTransaction(r.context(ctx), func(ctx context.Context) error {
+		before, err := r.find(ctx, EntityDeleted, id, true)
+		if err != nil {
+			return err
+		}
+
+		if err := r.db.Conn(ctx).Unscoped().Delete(new(T), "id = ?", id).Error; err != nil {
+			return err
+		}
+		return r.publish(ctx, EntityDeleted, before, nil, true)
+	})
+	if err != nil {
+		return r.fail("Failed to hard delete entity", err)
 	}
 	return nil
 }
 
-func (r *BaseRepository[T]) FindAll(ctx context.Context, query collectionquery.CollectionQuery) PaginatedResult[T] {
-	const defaultPageSize = 10
-	const defaultSkip = 0
-
-	pageSize := defaultPageSize
-	if query.Take != nil && *query.Take > 0 {
-		pageSize = *query.Take
+func (r *BaseRepository[T]) Restore(ctx context.Context, id uuid.UUID) error {
+	restored, err := r.RestoreMany(ctx, []uuid.UUID{id})
+	if err != nil {
+		return err
 	}
+	if restored == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
 
-	skip := defaultSkip
-	if query.Skip != nil && *query.Skip >= 0 {
-		skip = *query.Skip
+func (r *BaseRepository[T]) RestoreMany(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
 	}
 
-	qc := collectionquery.QueryConstructor[T]{}
+	var restored int64
+	err := r.tx.WithinTransaction(r.context(ctx), func(ctx context.Context) error {
+		tx := r.db.Conn(ctx)
+		sch, err := parseSchema(tx, new(T))
+		if err != nil {
+			return err
+		}
+
+		var archived []struct {
+			ID        uuid.UUID
+			DeletedAt time.Time
+		}
+		err = tx.Unscoped().Model(new(T)).
+			Select("id", "deleted_at").
+			Where("id IN ? AND deleted_at IS NOT NULL", ids).
+			Scan(&archived).Error
+		if err != nil {
+			return err
+		}
 
-	result, err := qc.Find(r.db.WithContext(ctx), query, false)
+		for _, row := range archived {
+			before, err := r.find(ctx, EntityUpdated, row.ID, true)
+			if err != nil {
+				return err
+			}
+
+			if err := cascadeRestore(tx, sch, row.ID, row.DeletedAt); err != nil {
+				return err
+			}
+			result := tx.Unscoped().Model(new(T)).Where("id = ?", row.ID).Update("deleted_at", nil)
+			if result.Error != nil {
+				return result.Error
+			}
+			restored += result.RowsAffected
+
+			after, err := r.find(ctx, EntityUpdated, row.ID, false)
+			if err != nil {
+				return err
+			}
+			if err := r.publish(ctx, EntityUpdated, before, after, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		r.logger.Error("Failed to find all entities", core.Error(err))
-		return PaginatedResult[T]{}
+		return 0, r.fail("Failed to restore entities", err)
 	}
+	return restored, nil
+}
 
-	if result.Total == 0 {
-		return PaginatedResult[T]{
-			Total:      0,
-			Data:       result.Items,
-			Page:       1,
-			PageSize:   pageSize,
-			TotalPages: 0,
+func (r *BaseRepository[T]) PurgeArchived(ctx context.Context, olderThan time.Duration) (int64, error) {
+	cutoff := time.Now().UTC().Add(-olderThan)
+
+	var purged int64
+	for {
+		var batch int64
+		err := r.tx.WithinTransaction(r.context(ctx), func(ctx context.Context) error {
+			tx := r.db.Conn(ctx)
+			sch, err := parseSchema(tx, new(T))
+			if err != nil {
+				return err
+			}
+
+			var archived []*T
+			err = tx.Unscoped().
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+				Limit(purgeBatchSize).
+				Find(&archived).Error
+			if err != nil || len(archived) == 0 {
+				return err
+			}
+
+			ids := make([]uuid.UUID, len(archived))
+			for i, entity := range archived {
+				ids[i] = entityID(sch, entity)
+			}
+
+			if err := cascadePurge(tx, sch, ids); err != nil {
+				return err
+			}
+			result := tx.Unscoped().Where("id IN ?", ids).Delete(new(T))
+			if result.Error != nil {
+				return result.Error
+			}
+			batch = result.RowsAffected
+
+			for _, entity := range archived {
+				if err := r.publish(ctx, EntityDeleted, entity, nil, true); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return purged, r.fail("Failed to purge archived entities", err)
 		}
-	}
 
-	var totalPages int
-	if pageSize > 0 {
-		totalPages = int(math.Ceil(float64(result.Total) / float64(pageSize)))
+		purged += batch
+		if batch < purgeBatchSize {
+			return purged, nil
+		}
 	}
+}
 
-	currentPage := (skip / pageSize) + 1
-
-	return PaginatedResult[T]{
-		Total:      result.Total,
-		Data:       result.Items,
-		Page:       currentPage,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+func (r *BaseRepository[T]) FindAll(
+	ctx context.Context,
+	query collectionquery.CollectionQuery,
+	opts ...FindOption,
+) (PaginatedResult[T], error) {
+	var result PaginatedResult[T]
+	err := r.read(ctx, "find all", func(ctx context.Context) (err error) {
+		result, err = r.paginate(ctx, query, false, newFindOptions(opts))
+		return err
+	})
+	if err != nil {
+		return PaginatedResult[T]{}, r.fail("Failed to find all entities", err)
 	}
+	return result, nil
 }
 
 func (r *BaseRepository[T]) FindAllArchived(
 	ctx context.Context,
 	query collectionquery.CollectionQuery,
-) PaginatedResult[T] {
+	opts ...FindOption,
+) (PaginatedResult[T], error) {
 	query.Where = append(query.Where, []collectionquery.Where{
 		{
-			Column:   "deletedAt",
+			Column:   "deleted_at",
 			Value:    "",
 			Operator: collectionquery.IsNotNull,
 		},
 	})
 
-	const defaultPageSize = 10
-	const defaultSkip = 0
-
-	pageSize := defaultPageSize
-	if query.Take != nil && *query.Take > 0 {
-		pageSize = *query.Take
-	}
-
-	skip := defaultSkip
-	if query.Skip != nil && *query.Skip >= 0 {
-		skip = *query.Skip
-	}
-
-	qc := collectionquery.QueryConstructor[T]{}
-	result, err := qc.Find(r.db.WithContext(ctx), query, true)
+	var result PaginatedResult[T]
+	err := r.read(ctx, "find all archived", func(ctx context.Context) (err error) {
+		result, err = r.paginate(ctx, query, true, newFindOptions(opts))
+		return err
+	})
 	if err != nil {
-		r.logger.Error("Failed to find all entities", core.Error(err))
-		return PaginatedResult[T]{}
-	}
-
-	if result.Total == 0 {
-		return PaginatedResult[T]{
-			Total:      0,
-			Data:       result.Items,
-			Page:       1,
-			PageSize:   pageSize,
-			TotalPages: 0,
-		}
-	}
-
-	var totalPages int
-	if pageSize > 0 {
-		totalPages = int(math.Ceil(float64(result.Total) / float64(pageSize)))
-	}
-
-	currentPage := (skip / pageSize) + 1
-
-	return PaginatedResult[T]{
-		Total:      result.Total,
-		Data:       result.Items,
-		Page:       currentPage,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+		return PaginatedResult[T]{}, r.fail("Failed to find archived entities", err)
 	}
+	return result, nil
 }
 
 func (r *BaseRepository[T]) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]*T, error) {
 	var entities []*T
-	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&entities).Error; err != nil {
-		r.logger.Error("Failed to find entities by IDs", core.Error(err))
-		return nil, err
+	err := r.read(ctx, "find by ids", func(ctx context.Context) error {
+		return r.conn(ctx).Where("id IN ?", ids).Find(&entities).Error
+	})
+	if err != nil {
+		return nil, r.fail("Failed to find entities by IDs", err)
 	}
 	return entities, nil
 }
 
 func (r *BaseRepository[T]) Count(ctx context.Context) (int64, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).Model(new(T)).Count(&count).Error; err != nil {
-		r.logger.Error("Failed to count entities", core.Error(err))
-		return 0, err
+	err := r.read(ctx, "count", func(ctx context.Context) error {
+		return r.conn(ctx).Model(new(T)).Count(&count).Error
+	})
+	if err != nil {
+		return 0, r.fail("Failed to count entities", err)
 	}
 	return count, nil
 }
 
 func (r *BaseRepository[T]) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).Model(new(T)).Where("id = ?", id).Count(&count).Error; err != nil {
-		r.logger.Error("Failed to check entity existence", core.Error(err))
-		return false, err
+	err := r.read(ctx, "exists", func(ctx context.Context) error {
+		return r.conn(ctx).Model(new(T)).Where("id = ?", id).Count(&count).Error
+	})
+	if err != nil {
+		return false, r.fail("Failed to check entity existence", err)
 	}
 
 	return count > 0, nil
 }
 
 func (r *BaseRepository[T]) Transaction(ctx context.Context, fn func(repo GenericRepository[T]) error) error {
-	return r.db.Transaction(ctx, func(tx *gorm.DB) error {
-		txRepo := &BaseRepository[T]{
-			db:     &core.Database{DB: tx},
-			logger: r.logger,
-		}
-		return fn(txRepo)
+	err := r.tx.WithinTransaction(r.context(ctx), func(ctx context.Context) error {
+		txRepo := *r
+		txRepo.txCtx = ctx
+		return fn(&txRepo)
 	})
+	return TranslateError(err)
 }
 
 func (r *BaseRepository[T]) GetDB() *gorm.DB {
 	return r.db.DB
 }
+
+// fail translates err and logs it unless it is an expected, typed failure
+func (r *BaseRepository[T]) fail(msg string, err error) error {
+	err = TranslateError(err)
+	if !IsExpected(err) {
+		r.logger.Error(msg, core.Error(err))
+	}
+	return err
+}
+
+// read runs an idempotent read, retried on transient failures when ctx opted
+// in with core.WithRetry
+func (r *BaseRepository[T]) read(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	return r.db.Retry(r.context(ctx), operation, fn)
+}
+
+// context joins the transaction this repository is bound to, if any
+func (r *BaseRepository[T]) context(ctx context.Context) context.Context {
+	if r.txCtx == nil {
+		return ctx
+	}
+	return core.JoinTx(ctx, r.txCtx)
+}
+
+// conn returns the connection (or transaction) to run a query on
+func (r *BaseRepository[T]) conn(ctx context.Context) *gorm.DB {
+	return r.db.Conn(r.context(ctx))
+}
+
+// find loads a snapshot of an entity for the event of action. It returns nil
+// when nobody listens for action on T or the entity does not exist.
+func (r *BaseRepository[T]) find(ctx context.Context, action EntityAction, id uuid.UUID, unscoped bool) (*T, error) {
+	if !r.observed(action) {
+		return nil, nil
+	}
+
+	var entity T
+	query := r.db.Conn(ctx)
+	if unscoped {
+		query = query.Unscoped()
+	}
+	if err := query.First(&entity, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// observed reports whether any subscriber listens for action on T
+func (r *BaseRepository[T]) observed(action EntityAction) bool {
+	return r.events.HasSubscribers(EntityTopic(EntityName[T](r.db.DB), action))
+}
+
+// publish emits an EntityEvent for T with copies of the before/after states
+func (r *BaseRepository[T]) publish(ctx context.Context, action EntityAction, before, after *T, permanent bool) error {
+	if !r.observed(action) {
+		return nil
+	}
+
+	sch, err := parseSchema(r.db.DB, new(T))
+	if err != nil {
+		return err
+	}
+
+	event := EntityEvent[T]{
+		Action:    action,
+		Entity:    sch.Table,
+		Before:    snapshot(before),
+		After:     snapshot(after),
+		Permanent: permanent,
+	}
+	if after != nil {
+		event.ID = entityID(sch, after)
+	} else if before != nil {
+		event.ID = entityID(sch, before)
+	}
+
+	return r.events.Publish(ctx, event)
+}
+
+func snapshot[T any](entity *T) *T {
+	if entity == nil {
+		return nil
+	}
+	clone := *entity
+	return &clone
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	onDeleteCascade = "CASCADE"
+	onDeleteSetNull = "SET NULL"
+)
+
+// schemaCache is shared by all repositories, as required by schema.Parse
+var schemaCache = &sync.Map{}
+
+// cascadeRelation is a has-one/has-many relation whose rows follow the
+// lifecycle of their owner, as declared by the GORM constraint tag.
+type cascadeRelation struct {
+	schema     *schema.Schema
+	foreignKey string
+	onDelete   string
+}
+
+func (c cascadeRelation) model() any {
+	return reflect.New(c.schema.ModelType).Interface()
+}
+
+func (c cascadeRelation) softDeletable() bool {
+	return hasDeletedAt(c.schema)
+}
+
+func parseSchema(db *gorm.DB, model any) (*schema.Schema, error) {
+	sch, err := schema.Parse(model, schemaCache, db.NamingStrategy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	return sch, nil
+}
+
+func hasDeletedAt(sch *schema.Schema) bool {
+	_, ok := sch.FieldsByDBName["deleted_at"]
+	return ok
+}
+
+// cascadeRelations returns the relations of sch declaring ON DELETE CASCADE or
+// SET NULL. Relations without such a constraint are left to the database.
+func cascadeRelations(sch *schema.Schema) []cascadeRelation {
+	var relations []cascadeRelation
+
+	owned := append(append([]*schema.Relationship{}, sch.Relationships.HasOne...), sch.Relationships.HasMany...)
+	for _, rel := range owned {
+		if rel.Polymorphic != nil || len(rel.References) != 1 || !rel.References[0].OwnPrimaryKey {
+			continue
+		}
+
+		constraint := rel.ParseConstraint()
+		if constraint == nil {
+			continue
+		}
+
+		onDelete := strings.ToUpper(constraint.OnDelete)
+		if onDelete != onDeleteCascade && onDelete != onDeleteSetNull {
+			continue
+		}
+
+		relations = append(relations, cascadeRelation{
+			schema:     rel.FieldSchema,
+			foreignKey: rel.References[0].ForeignKey.DBName,
+			onDelete:   onDelete,
+		})
+	}
+
+	return relations
+}
+
+// cascadeSoftDelete soft deletes the cascading children of the given owners.
+// Owners must be deleted first so children are never archived before them.
+func cascadeSoftDelete(tx *gorm.DB, sch *schema.Schema, ownerIDs []uuid.UUID) error {
+	for _, rel := range cascadeRelations(sch) {
+		if rel.onDelete != onDeleteCascade || !rel.softDeletable() {
+			continue
+		}
+
+		childIDs, err := pluckIDs(tx, rel, ownerIDs)
+		if err != nil {
+			return err
+		}
+		if len(childIDs) == 0 {
+			continue
+		}
+
+		if err := tx.Where("id IN ?", childIDs).Delete(rel.model()).Error; err != nil {
+			return fmt.Errorf("failed to cascade delete to %s: %w", rel.schema.Table, err)
+		}
+		if err := cascadeSoftDelete(tx, rel.schema, childIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cascadeRestore restores the cascading children of an owner that were
+// archived at or after the owner itself, leaving earlier deletions untouched.
+func cascadeRestore(tx *gorm.DB, sch *schema.Schema, ownerID uuid.UUID, deletedAt time.Time) error {
+	for _, rel := range cascadeRelations(sch) {
+		if rel.onDelete != onDeleteCascade || !rel.softDeletable() {
+			continue
+		}
+
+		var childIDs []uuid.UUID
+		err := tx.Unscoped().Model(rel.model()).
+			Where(fmt.Sprintf("%s = ?", rel.foreignKey), ownerID).
+			Where("deleted_at >= ?", deletedAt).
+			Pluck("id", &childIDs).Error
+		if err != nil {
+			return fmt.Errorf("failed to load archived %s: %w", rel.schema.Table, err)
+		}
+
+		for _, childID := range childIDs {
+			if err := cascadeRestore(tx, rel.schema, childID, deletedAt); err != nil {
+				return err
+			}
+		}
+		if len(childIDs) == 0 {
+			continue
+		}
+
+		err = tx.Unscoped().Model(rel.model()).
+			Where("id IN ?", childIDs).
+			Update("deleted_at", nil).Error
+		if err != nil {
+			return fmt.Errorf("failed to cascade restore to %s: %w", rel.schema.Table, err)
+		}
+	}
+	return nil
+}
+
+// cascadePurge permanently removes (or detaches, for SET NULL) the children of
+// the given owners, deepest relations first so foreign keys never dangle.
+func cascadePurge(tx *gorm.DB, sch *schema.Schema, ownerIDs []uuid.UUID) error {
+	for _, rel := range cascadeRelations(sch) {
+		where := fmt.Sprintf("%s IN ?", rel.foreignKey)
+
+		if rel.onDelete == onDeleteSetNull {
+			err := tx.Unscoped().Model(rel.model()).
+				Where(where, ownerIDs).
+				Update(rel.foreignKey, nil).Error
+			if err != nil {
+				return fmt.Errorf("failed to detach %s: %w", rel.schema.Table, err)
+			}
+			continue
+		}
+
+		if _, ok := rel.schema.FieldsByDBName["id"]; ok {
+			childIDs, err := pluckIDs(tx.Unscoped(), rel, ownerIDs)
+			if err != nil {
+				return err
+			}
+			if len(childIDs) > 0 {
+				if err := cascadePurge(tx, rel.schema, childIDs); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Unscoped().Where(where, ownerIDs).Delete(rel.model()).Error; err != nil {
+			return fmt.Errorf("failed to cascade purge to %s: %w", rel.schema.Table, err)
+		}
+	}
+	return nil
+}
+
+func pluckIDs(tx *gorm.DB, rel cascadeRelation, ownerIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := tx.Model(rel.model()).
+		Where(fmt.Sprintf("%s IN ?", rel.foreignKey), ownerIDs).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", rel.schema.Table, err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	collectionquery "github.com/johna210/go-next-flutter/pkg/collection_query"
+)
+
+var (
+	ErrNotFound                = errors.New("record not found")
+	ErrAlreadyExists           = core.ErrAlreadyExists
+	ErrForeignKeyViolation     = core.ErrForeignKeyViolation
+	ErrNotNullViolation        = core.ErrNotNullViolation
+	ErrCheckViolation          = core.ErrCheckViolation
+	ErrSerializationFailure    = core.ErrSerializationFailure
+	ErrDeadlock                = core.ErrDeadlock
+	ErrLockNotAvailable        = core.ErrLockNotAvailable
+	ErrLockRequiresTransaction = errors.New("row locks require a transaction")
+	ErrLockNotSupported        = errors.New("row locks are not supported by this database")
+	ErrQueryTimeout            = core.ErrQueryTimeout
+	ErrQueryCanceled           = core.ErrQueryCanceled
+	ErrNoPrimaryKey            = errors.New("entity has no single primary key")
+)
+
+// DBError is a database failure translated by TranslateError. It matches its
+// Kind (e.g. ErrAlreadyExists) and the original driver error with errors.Is/As.
+type DBError = core.DBError
+
+// TranslateError maps constraint, concurrency, locking and timeout failures
+// to a *DBError (see core.TranslateDBError). Other errors are returned unchanged.
+func TranslateError(err error) error {
+	return core.TranslateDBError(err)
+}
+
+// IsExpected reports whether err is a translated, client-facing failure
+// (conflict, missing record, lock contention) rather than a fault worth logging.
+func IsExpected(err error) bool {
+	var dbErr *DBError
+	return errors.As(err, &dbErr) ||
+		errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrLockRequiresTransaction) ||
+		errors.Is(err, ErrLockNotSupported) ||
+		errors.Is(err, collectionquery.ErrUnsupportedOperator)
+}
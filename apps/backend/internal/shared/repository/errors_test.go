@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlServerTestError mimics the errors of go-mssqldb
+type sqlServerTestError struct {
+	number  int32
+	message string
+}
+
+func (e sqlServerTestError) Error() string           { return "mssql: " + e.message }
+func (e sqlServerTestError) SQLErrorNumber() int32   { return e.number }
+func (e sqlServerTestError) SQLErrorMessage() string { return e.message }
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		kind       error
+		table      string
+		constraint string
+		column     string
+	}{
+		{
+			name: "postgres unique violation",
+			err: &pgconn.PgError{Code: "23505", TableName: "users", ConstraintName: "idx_users_email",
+				Detail: "Key (email)=(a@b.c) already exists."},
+			kind: ErrAlreadyExists, table: "users", constraint: "idx_users_email", column: "email",
+		},
+		{
+			name: "postgres not null prefers the column name",
+			err:  &pgconn.PgError{Code: "23502", TableName: "users", ColumnName: "name"},
+			kind: ErrNotNullViolation, table: "users", column: "name",
+		},
+		{
+			name: "postgres foreign key",
+			err:  &pgconn.PgError{Code: "23503", ConstraintName: "fk_orders_user"},
+			kind: ErrForeignKeyViolation, constraint: "fk_orders_user",
+		},
+		{name: "postgres deadlock", err: &pgconn.PgError{Code: "40P01"}, kind: ErrDeadlock},
+		{name: "postgres lock not available", err: &pgconn.PgError{Code: "55P03"}, kind: ErrLockNotAvailable},
+		{
+			name: "mysql duplicate entry",
+			err:  &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.c' for key 'users.idx_users_email'"},
+			kind: ErrAlreadyExists, constraint: "users.idx_users_email",
+		},
+		{
+			name: "mysql bad null",
+			err:  &mysql.MySQLError{Number: 1048, Message: "Column 'email' cannot be null"},
+			kind: ErrNotNullViolation, column: "email",
+		},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, kind: ErrDeadlock},
+		{name: "mysql lock wait timeout", err: &mysql.MySQLError{Number: 1205}, kind: ErrLockNotAvailable},
+		{
+			name: "sqlserver foreign key",
+			err:  sqlServerTestError{547, `The INSERT statement conflicted with the FOREIGN KEY constraint "fk_orders_user".`},
+			kind: ErrForeignKeyViolation, constraint: "fk_orders_user",
+		},
+		{
+			name: "sqlserver check constraint",
+			err:  sqlServerTestError{547, `The INSERT statement conflicted with the CHECK constraint "chk_orders_total".`},
+			kind: ErrCheckViolation, constraint: "chk_orders_total",
+		},
+		{name: "sqlserver deadlock", err: sqlServerTestError{1205, "Transaction was deadlocked"}, kind: ErrDeadlock},
+		{name: "sqlserver snapshot conflict", err: sqlServerTestError{3960, "Snapshot isolation transaction aborted"}, kind: ErrSerializationFailure},
+		{name: "postgres statement timeout", err: &pgconn.PgError{Code: "57014"}, kind: ErrQueryTimeout},
+		{name: "mysql max execution time", err: &mysql.MySQLError{Number: 3024}, kind: ErrQueryTimeout},
+		{name: "mysql query interrupted", err: &mysql.MySQLError{Number: 1317}, kind: ErrQueryCanceled},
+		{name: "deadline exceeded", err: fmt.Errorf("query: %w", context.DeadlineExceeded), kind: ErrQueryTimeout},
+		{name: "canceled", err: context.Canceled, kind: ErrQueryCanceled},
+		{name: "wrapped", err: fmt.Errorf("save: %w", &pgconn.PgError{Code: "40001"}), kind: ErrSerializationFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dbErr *DBError
+			if err := TranslateError(tt.err); !errors.As(err, &dbErr) {
+				t.Fatalf("TranslateError() = %v, want a *DBError", err)
+			}
+			if !errors.Is(dbErr, tt.kind) || !errors.Is(dbErr, tt.err) {
+				t.Fatalf("TranslateError() = %v, want it to match %v and the driver error", dbErr, tt.kind)
+			}
+			if dbErr.Table != tt.table || dbErr.Constraint != tt.constraint || dbErr.Column != tt.column {
+				t.Fatalf("TranslateError() table %q constraint %q column %q, want %q %q %q",
+					dbErr.Table, dbErr.Constraint, dbErr.Column, tt.table, tt.constraint, tt.column)
+			}
+		})
+	}
+}
+
+type errorsItem struct {
+	ID    int
+	Email string `gorm:"uniqueIndex;not null"`
+}
+
+func TestTranslateErrorSQLite(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&errorsItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&errorsItem{ID: 1, Email: "a@b.c"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	err = TranslateError(db.Create(&errorsItem{ID: 2, Email: "a@b.c"}).Error)
+	var dbErr *DBError
+	if !errors.As(err, &dbErr) || !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("TranslateError() = %v, want ErrAlreadyExists", err)
+	}
+	if dbErr.Table != "errors_items" || dbErr.Column != "email" {
+		t.Fatalf("TranslateError() table %q column %q, want errors_items email", dbErr.Table, dbErr.Column)
+	}
+
+	err = TranslateError(db.Exec("INSERT INTO errors_items (id, email) VALUES (3, NULL)").Error)
+	if !errors.Is(err, ErrNotNullViolation) {
+		t.Fatalf("TranslateError() = %v, want ErrNotNullViolation", err)
+	}
+}
+
+func TestTranslateErrorPassesThrough(t *testing.T) {
+	if TranslateError(nil) != nil {
+		t.Fatal("TranslateError(nil) != nil")
+	}
+
+	other := errors.New("boom")
+	if err := TranslateError(other); err != other {
+		t.Fatalf("TranslateError() = %v, want the error unchanged", err)
+	}
+	if err := TranslateError(&pgconn.PgError{Code: "42P01"}); errors.As(err, new(*DBError)) {
+		t.Fatalf("TranslateError() = %v, want unknown codes unchanged", err)
+	}
+
+	translated := TranslateError(&pgconn.PgError{Code: "40P01"})
+	wrapped := fmt.Errorf("save: %w", translated)
+	if err := TranslateError(wrapped); err != wrapped {
+		t.Fatalf("TranslateError() = %v, want translated errors unchanged", err)
+	}
+	if !IsExpected(wrapped) {
+		t.Fatal("IsExpected() = false for a translated error")
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+)
+
+// EntityAction describes what happened to an entity
+type EntityAction string
+
+const (
+	EntityCreated EntityAction = "created"
+	EntityUpdated EntityAction = "updated"
+	EntityDeleted EntityAction = "deleted"
+)
+
+// EntityChange is the untyped view of an EntityEvent, for subscribers that
+// handle every entity (audit logs, outbox, webhooks).
+type EntityChange interface {
+	core.Event
+	EntityName() string
+	EntityID() uuid.UUID
+	EntityAction() EntityAction
+	Snapshots() (before, after any)
+}
+
+// EntityEvent is published by BaseRepository once a change to T is committed.
+// Before is nil for creations and After is nil for deletions.
+type EntityEvent[T any] struct {
+	Action    EntityAction
+	Entity    string
+	ID        uuid.UUID
+	Before    *T
+	After     *T
+	Permanent bool // set for hard deletes and purges
+}
+
+func (e EntityEvent[T]) Topic() string              { return EntityTopic(e.Entity, e.Action) }
+func (e EntityEvent[T]) EntityName() string         { return e.Entity }
+func (e EntityEvent[T]) EntityID() uuid.UUID        { return e.ID }
+func (e EntityEvent[T]) EntityAction() EntityAction { return e.Action }
+
+func (e EntityEvent[T]) Snapshots() (before, after any) {
+	if e.Before != nil {
+		before = e.Before
+	}
+	if e.After != nil {
+		after = e.After
+	}
+	return before, after
+}
+
+// EntityTopic returns the event topic for an entity action, e.g. "users.created"
+func EntityTopic(entity string, action EntityAction) string {
+	return fmt.Sprintf("%s.%s", entity, action)
+}
+
+// OnEntity subscribes a typed handler to every change of T stored in db
+func OnEntity[T any](
+	bus core.EventBus,
+	db *core.Database,
+	handler func(ctx context.Context, event EntityEvent[T]) error,
+	opts ...core.SubscribeOption,
+) {
+	bus.Subscribe(EntityName[T](db.DB)+".*", func(ctx context.Context, event core.Event) error {
+		typed, ok := event.(EntityEvent[T])
+		if !ok {
+			return nil
+		}
+		return handler(ctx, typed)
+	}, opts...)
+}
+
+// EntityName returns the name events of T are published under: its table
+// name under the naming strategy of db
+func EntityName[T any](db *gorm.DB) string {
+	sch, err := parseSchema(db, new(T))
+	if err != nil {
+		return reflect.TypeFor[T]().Name()
+	}
+	return sch.Table
+}
+
+// entityID reads the uuid primary key of entity, or uuid.Nil if it has none
+func entityID(sch *schema.Schema, entity any) uuid.UUID {
+	field := sch.PrioritizedPrimaryField
+	if field == nil {
+		return uuid.Nil
+	}
+
+	value, zero := field.ValueOf(context.Background(), reflect.Indirect(reflect.ValueOf(entity)))
+	if zero {
+		return uuid.Nil
+	}
+	id, _ := value.(uuid.UUID)
+	return id
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"iter"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -17,8 +19,8 @@ type GenericRepository[T any] interface {
 	// BulkCreate creates multiple entities
 	BulkCreate(ctx context.Context, entities []*T) error
 
-	// GetByID retrieves an entity by ID
-	GetByID(ctx context.Context, id uuid.UUID) (*T, error)
+	// GetByID retrieves an entity by ID, optionally locking its row (see WithLock)
+	GetByID(ctx context.Context, id uuid.UUID, opts ...FindOption) (*T, error)
 
 	// Update updates an entity
 	Update(ctx context.Context, entity *T) error
@@ -29,11 +31,29 @@ type GenericRepository[T any] interface {
 	// HardDelete permanently deletes an entity
 	HardDelete(ctx context.Context, id uuid.UUID) error
 
-	// FindAll retrieves all entities with pagination
-	FindAll(ctx context.Context, query collectionquery.CollectionQuery) PaginatedResult[T]
+	// Restore brings back a soft deleted entity together with its cascading relations
+	Restore(ctx context.Context, id uuid.UUID) error
 
-	// FindAllArchived retrieves all archived (soft deleted) entities with pagination
-	FindAllArchived(ctx context.Context, query collectionquery.CollectionQuery) PaginatedResult[T]
+	// RestoreMany restores multiple soft deleted entities and returns how many were restored
+	RestoreMany(ctx context.Context, ids []uuid.UUID) (int64, error)
+
+	// PurgeArchived permanently deletes entities soft deleted longer than olderThan ago
+	PurgeArchived(ctx context.Context, olderThan time.Duration) (int64, error)
+
+	// FindAll retrieves a page of entities, optionally locking the returned rows
+	// (see WithLock) or changing how the total is counted (see WithCountStrategy)
+	FindAll(ctx context.Context, query collectionquery.CollectionQuery, opts ...FindOption) (PaginatedResult[T], error)
+
+	// FindAllArchived retrieves a page of archived (soft deleted) entities
+	FindAllArchived(ctx context.Context, query collectionquery.CollectionQuery, opts ...FindOption) (PaginatedResult[T], error)
+
+	// Iterate streams the entities matching query in primary key order, fetching
+	// them in batches (see WithBatchSize) so large tables never sit in memory.
+	// Skip and OrderBy are ignored; Take caps the total number of entities.
+	Iterate(ctx context.Context, query collectionquery.CollectionQuery, opts ...FindOption) iter.Seq2[*T, error]
+
+	// Each calls fn for every entity matching query, stopping at the first error
+	Each(ctx context.Context, query collectionquery.CollectionQuery, fn func(entity *T) error, opts ...FindOption) error
 
 	// FindByIDs retrieves multiple entities by IDs
 	FindByIDs(ctx context.Context, ids []uuid.UUID) ([]*T, error)
@@ -44,7 +64,8 @@ type GenericRepository[T any] interface {
 	// Exists checks if an entity exists
 	Exists(ctx context.Context, id uuid.UUID) (bool, error)
 
-	// Transaction executes a function within a transaction
+	// Transaction executes a function within a transaction. Use core.TxManager
+	// to span a transaction across several repositories.
 	Transaction(ctx context.Context, fn func(repo GenericRepository[T]) error) error
 
 	// GetDB returns the underlying GORM DB instance for custom queries
@@ -60,10 +81,15 @@ type QueryOptions struct {
 	IncludeDeleted bool                   // Include soft deleted records
 }
 
+// PaginatedResult is a page of entities. Total is an estimate when
+// TotalEstimated is set and -1 when counting was skipped (CountNone).
 type PaginatedResult[T any] struct {
-	Data       []*T  `json:"data"`
-	Total      int64 `json:"total"`
-	Page       int   `json:"page"`
-	PageSize   int   `json:"page_size"`
-	TotalPages int   `json:"total_pages"`
+	Data           []*T  `json:"data"`
+	Total          int64 `json:"total"`
+	TotalEstimated bool  `json:"total_estimated"`
+	Page           int   `json:"page"`
+	PageSize       int   `json:"page_size"`
+	TotalPages     int   `json:"total_pages"`
+	HasNext        bool  `json:"has_next"`
+	HasPrev        bool  `json:"has_prev"`
 }
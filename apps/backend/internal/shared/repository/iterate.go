@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm/clause"
+
+	collectionquery "github.com/johna210/go-next-flutter/pkg/collection_query"
+)
+
+// defaultIterateBatchSize is used by Iterate and Each unless WithBatchSize is given
+const defaultIterateBatchSize = 500
+
+// Iterate pages through the matching rows with keyset pagination on the
+// primary key (WHERE pk > last ORDER BY pk), which stays fast on large tables
+// unlike OFFSET, and checks ctx between batches. Entities without a single
+// primary key, such as join tables keyed by two columns, yield
+// ErrNoPrimaryKey. WithLock locks each batch as it is read, so the lock is
+// held for every row yielded until the surrounding transaction ends.
+func (r *BaseRepository[T]) Iterate(
+	ctx context.Context,
+	query collectionquery.CollectionQuery,
+	opts ...FindOption,
+) iter.Seq2[*T, error] {
+	options := newFindOptions(opts)
+	batchSize := options.batchSize
+	if batchSize <= 0 {
+		batchSize = defaultIterateBatchSize
+	}
+
+	limit := 0
+	if query.Take != nil && *query.Take > 0 {
+		limit = *query.Take
+	}
+
+	return func(yield func(*T, error) bool) {
+		sch, err := parseSchema(r.db.DB, new(T))
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		pk := sch.PrioritizedPrimaryField
+		if pk == nil {
+			yield(nil, fmt.Errorf("iterate %s: %w", sch.Name, ErrNoPrimaryKey))
+			return
+		}
+		lock, err := lockClause(r.context(ctx), r.db.DB, options.lock)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		asc := collectionquery.Ascending
+		query.Skip = nil
+		query.Count = nil
+		query.OrderBy = []collectionquery.Order{{Column: pk.DBName, Direction: &asc}}
+		if len(query.Select) > 0 && !slices.Contains(query.Select, pk.DBName) {
+			query.Select = append(slices.Clone(query.Select), pk.DBName)
+		}
+
+		var (
+			lastKey any
+			fetched int
+		)
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			size := batchSize
+			if limit > 0 {
+				size = min(size, limit-fetched)
+			}
+			if size <= 0 {
+				return
+			}
+
+			db := r.conn(ctx)
+			if lock != nil {
+				db = db.Clauses(*lock)
+			}
+			if fetched > 0 {
+				db = db.Where(clause.Gt{
+					Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName},
+					Value:  lastKey,
+				})
+			}
+
+			batch := query
+			batch.Take = &size
+
+			qc := collectionquery.QueryConstructor[T]{}
+			var items []*T
+			if err := qc.ConstructQuery(db, batch, false).Find(&items).Error; err != nil {
+				yield(nil, r.fail("Failed to iterate entities", err))
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(items) < size {
+				return
+			}
+
+			fetched += len(items)
+			lastKey, _ = pk.ValueOf(ctx, reflect.ValueOf(items[len(items)-1]).Elem())
+		}
+	}
+}
+
+func (r *BaseRepository[T]) Each(
+	ctx context.Context,
+	query collectionquery.CollectionQuery,
+	fn func(entity *T) error,
+	opts ...FindOption,
+) error {
+	for entity, err := range r.Iterate(ctx, query, opts...) {
+		if err != nil {
+			return err
+		}
+		if err := fn(entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
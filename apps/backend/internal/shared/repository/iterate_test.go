@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	collectionquery "github.com/johna210/go-next-flutter/pkg/collection_query"
+)
+
+type iterateItem struct {
+	Code string `gorm:"primaryKey"`
+	Name string
+}
+
+type iterateLink struct {
+	LeftID  int
+	RightID int
+}
+
+func openTestDB(t *testing.T, models ...any) *core.Database {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return &core.Database{DB: db}
+}
+
+func TestIterateKeysOnPrimaryField(t *testing.T) {
+	db := openTestDB(t, &iterateItem{})
+	codes := []string{"g", "c", "a", "f", "b", "e", "d"}
+	for _, code := range codes {
+		if err := db.Create(&iterateItem{Code: code, Name: "item " + code}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := &BaseRepository[iterateItem]{db: db}
+
+	take := 5
+	var got []string
+	for item, err := range repo.Iterate(context.Background(),
+		collectionquery.CollectionQuery{Select: []string{"name"}, Take: &take}, WithBatchSize(2)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, item.Code)
+	}
+
+	want := []string{"a", "b", "c", "d", "e"}
+	if len(got) != len(want) {
+		t.Fatalf("Iterate() yielded %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Iterate() yielded %v, want %v", got, want)
+		}
+	}
+}
+
+func TestIterateRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  func(db *core.Database) error
+		want error
+	}{
+		{
+			name: "composite key",
+			seq: func(db *core.Database) error {
+				return firstErr((&BaseRepository[iterateLink]{db: db}).Iterate(
+					context.Background(), collectionquery.CollectionQuery{}))
+			},
+			want: ErrNoPrimaryKey,
+		},
+		{
+			name: "lock outside a transaction",
+			seq: func(db *core.Database) error {
+				return firstErr((&BaseRepository[iterateItem]{db: db}).Iterate(
+					context.Background(), collectionquery.CollectionQuery{}, WithLock(LockForUpdate)))
+			},
+			want: ErrLockRequiresTransaction,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t, &iterateItem{}, &iterateLink{})
+			if err := tt.seq(db); !errors.Is(err, tt.want) {
+				t.Fatalf("Iterate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func firstErr[T any](seq func(yield func(*T, error) bool)) error {
+	for _, err := range seq {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+)
+
+// LockMode selects the row lock a read takes on the rows it returns
+type LockMode int
+
+const (
+	LockNone LockMode = iota
+	// LockForUpdate waits for and takes an exclusive row lock
+	LockForUpdate
+	// LockForUpdateSkipLocked skips rows locked by other transactions
+	LockForUpdateSkipLocked
+	// LockForUpdateNoWait fails with ErrLockNotAvailable instead of waiting
+	LockForUpdateNoWait
+	// LockForShare takes a shared lock that blocks concurrent writers only
+	LockForShare
+)
+
+func (m LockMode) clause() (clause.Locking, bool) {
+	switch m {
+	case LockForUpdate:
+		return clause.Locking{Strength: clause.LockingStrengthUpdate}, true
+	case LockForUpdateSkipLocked:
+		return clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}, true
+	case LockForUpdateNoWait:
+		return clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsNoWait}, true
+	case LockForShare:
+		return clause.Locking{Strength: clause.LockingStrengthShare}, true
+	default:
+		return clause.Locking{}, false
+	}
+}
+
+// WithLock makes the read lock the rows it returns until the surrounding
+// transaction ends. Locked reads must run inside a transaction, either via
+// core.TxManager or GenericRepository.Transaction.
+func WithLock(mode LockMode) FindOption {
+	return func(o *findOptions) { o.lock = mode }
+}
+
+// lockClause returns the locking clause for mode, failing when ctx carries no
+// transaction since the lock would be released as soon as the read returns.
+func lockClause(ctx context.Context, db *gorm.DB, mode LockMode) (*clause.Locking, error) {
+	locking, ok := mode.clause()
+	if !ok {
+		return nil, nil
+	}
+	// SQL Server locks through table hints and rejects FOR UPDATE/SHARE
+	if db.Dialector.Name() == core.DBTypeSQLServer {
+		return nil, ErrLockNotSupported
+	}
+	if _, inTx := core.TxFromContext(ctx); !inTx {
+		return nil, ErrLockRequiresTransaction
+	}
+	return &locking, nil
+}
@@ -0,0 +1,28 @@
+package repository
+
+// FindOption configures a read made through GenericRepository
+type FindOption func(*findOptions)
+
+type findOptions struct {
+	lock      LockMode
+	batchSize int
+	count     CountStrategy
+}
+
+// WithBatchSize sets how many rows Iterate and Each fetch per query
+func WithBatchSize(n int) FindOption {
+	return func(o *findOptions) { o.batchSize = n }
+}
+
+// WithCountStrategy sets how FindAll and FindAllArchived compute the total
+func WithCountStrategy(strategy CountStrategy) FindOption {
+	return func(o *findOptions) { o.count = strategy }
+}
+
+func newFindOptions(opts []FindOption) findOptions {
+	var options findOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"math"
+
+	"gorm.io/gorm"
+
+	"github.com/johna210/go-next-flutter/internal/core"
+	collectionquery "github.com/johna210/go-next-flutter/pkg/collection_query"
+)
+
+const (
+	defaultPageSize = 10
+
+	// totalSkipped is reported as Total when counting is disabled
+	totalSkipped = -1
+)
+
+// CountStrategy controls how paginated reads compute their total
+type CountStrategy int
+
+const (
+	// CountExact runs COUNT(*) over the filtered query
+	CountExact CountStrategy = iota
+	// CountEstimated reads the planner's row estimate (pg_class.reltuples) for
+	// unfiltered queries, which is instant on big tables. Filtered queries,
+	// tables that were never analyzed and databases other than Postgres fall
+	// back to an exact count.
+	CountEstimated
+	// CountNone skips counting; HasNext is found by fetching one extra row
+	CountNone
+)
+
+// paginate runs query and builds the page metadata shared by FindAll and FindAllArchived
+func (r *BaseRepository[T]) paginate(
+	ctx context.Context,
+	query collectionquery.CollectionQuery,
+	unscoped bool,
+	options findOptions,
+) (PaginatedResult[T], error) {
+	pageSize := defaultPageSize
+	if query.Take != nil && *query.Take > 0 {
+		pageSize = *query.Take
+	}
+	skip := 0
+	if query.Skip != nil && *query.Skip >= 0 {
+		skip = *query.Skip
+	}
+
+	lock, err := lockClause(r.context(ctx), r.db.DB, options.lock)
+	if err != nil {
+		return PaginatedResult[T]{}, err
+	}
+
+	qc := collectionquery.QueryConstructor[T]{Lock: lock}
+	countOnly := query.Count != nil && *query.Count
+	query.Count = nil
+
+	total, estimated, err := r.countTotal(ctx, qc, query, unscoped, options.count, countOnly)
+	if err != nil {
+		return PaginatedResult[T]{}, err
+	}
+
+	result := PaginatedResult[T]{
+		Total:          total,
+		TotalEstimated: estimated,
+		Page:           skip/pageSize + 1,
+		PageSize:       pageSize,
+		HasPrev:        skip > 0,
+	}
+	if total > 0 {
+		result.TotalPages = int(math.Ceil(float64(total) / float64(pageSize)))
+	}
+	if countOnly {
+		return result, nil
+	}
+
+	// Without an exact total, probe one row past the page to detect a next page
+	probe := total == totalSkipped || estimated
+	take := pageSize
+	if probe {
+		take++
+	}
+	query.Take = &take
+	query.Skip = &skip
+
+	qb := qc.ConstructQuery(r.conn(ctx), query, unscoped)
+	if lock != nil {
+		qb = qb.Clauses(*lock)
+	}
+	var items []*T
+	if err := qb.Find(&items).Error; err != nil {
+		return PaginatedResult[T]{}, err
+	}
+
+	if probe {
+		result.HasNext = len(items) > pageSize
+		items = items[:min(len(items), pageSize)]
+	} else {
+		result.HasNext = int64(skip+len(items)) < total
+	}
+	result.Data = items
+	return result, nil
+}
+
+// countTotal computes the total for strategy, or totalSkipped for CountNone.
+// Count-only queries are always counted exactly.
+func (r *BaseRepository[T]) countTotal(
+	ctx context.Context,
+	qc collectionquery.QueryConstructor[T],
+	query collectionquery.CollectionQuery,
+	unscoped bool,
+	strategy CountStrategy,
+	countOnly bool,
+) (total int64, estimated bool, err error) {
+	if !countOnly {
+		switch strategy {
+		case CountNone:
+			return totalSkipped, false, nil
+		case CountEstimated:
+			if len(query.Where) == 0 && len(query.GroupBy) == 0 {
+				if total, ok, err := r.estimateCount(ctx); err != nil || ok {
+					return total, ok, err
+				}
+			}
+		}
+	}
+
+	query.Skip = nil
+	query.Take = nil
+	err = qc.ConstructQuery(r.conn(ctx), query, unscoped).
+		Session(&gorm.Session{}).
+		Count(&total).Error
+	return total, false, err
+}
+
+// estimateCount reads the planner's row estimate for T's table. It reports
+// false when no estimate exists yet (the table was never analyzed) or the
+// database is not Postgres.
+func (r *BaseRepository[T]) estimateCount(ctx context.Context) (int64, bool, error) {
+	if r.db.Dialector.Name() != core.DBTypePostgres {
+		return 0, false, nil
+	}
+
+	sch, err := parseSchema(r.db.DB, new(T))
+	if err != nil {
+		return 0, false, err
+	}
+
+	estimate := -1.0
+	err = r.conn(ctx).
+		Raw("SELECT reltuples FROM pg_class WHERE oid = to_regclass(?)", sch.Table).
+		Scan(&estimate).Error
+	if err != nil || estimate < 0 {
+		return 0, false, err
+	}
+	return int64(estimate), true, nil
+}
@@ -0,0 +1,6 @@
+-- Modify "sessions" table
+ALTER TABLE "sessions" ADD CONSTRAINT "fk_users_sessions" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+-- Modify "user_profiles" table
+ALTER TABLE "user_profiles" DROP CONSTRAINT "fk_users_profile", ADD CONSTRAINT "fk_users_profile" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE CASCADE ON DELETE CASCADE;
+-- Modify "user_roles" table
+ALTER TABLE "user_roles" DROP CONSTRAINT "fk_users_roles", ADD CONSTRAINT "fk_users_roles" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE;
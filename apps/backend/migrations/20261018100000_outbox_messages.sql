@@ -0,0 +1,23 @@
+-- Create "outbox_messages" table
+CREATE TABLE "outbox_messages" (
+  "id" uuid NOT NULL DEFAULT uuid_generate_v4(),
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "deleted_at" timestamptz NULL,
+  "topic" text NOT NULL,
+  "aggregate_type" text NOT NULL DEFAULT '',
+  "aggregate_id" uuid NULL,
+  "payload" jsonb NOT NULL,
+  "status" character varying(16) NOT NULL DEFAULT 'pending',
+  "attempts" bigint NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "last_error" text NULL,
+  "delivered_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_outbox_messages_deleted_at" to table: "outbox_messages"
+CREATE INDEX "idx_outbox_messages_deleted_at" ON "outbox_messages" ("deleted_at");
+-- Create index "idx_outbox_messages_due" to table: "outbox_messages"
+CREATE INDEX "idx_outbox_messages_due" ON "outbox_messages" ("status", "next_attempt_at");
+-- Create index "idx_outbox_messages_topic" to table: "outbox_messages"
+CREATE INDEX "idx_outbox_messages_topic" ON "outbox_messages" ("topic");
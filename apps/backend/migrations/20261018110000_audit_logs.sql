@@ -0,0 +1,22 @@
+-- Create "audit_logs" table
+CREATE TABLE "audit_logs" (
+  "id" uuid NOT NULL DEFAULT uuid_generate_v4(),
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "deleted_at" timestamptz NULL,
+  "actor_id" uuid NULL,
+  "tenant_id" text NOT NULL DEFAULT '',
+  "entity_type" text NOT NULL,
+  "entity_id" uuid NOT NULL,
+  "action" character varying(16) NOT NULL,
+  "changes" jsonb NOT NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_audit_logs_actor_id" to table: "audit_logs"
+CREATE INDEX "idx_audit_logs_actor_id" ON "audit_logs" ("actor_id");
+-- Create index "idx_audit_logs_deleted_at" to table: "audit_logs"
+CREATE INDEX "idx_audit_logs_deleted_at" ON "audit_logs" ("deleted_at");
+-- Create index "idx_audit_logs_entity" to table: "audit_logs"
+CREATE INDEX "idx_audit_logs_entity" ON "audit_logs" ("entity_type", "entity_id");
+-- Create index "idx_audit_logs_tenant_id" to table: "audit_logs"
+CREATE INDEX "idx_audit_logs_tenant_id" ON "audit_logs" ("tenant_id");
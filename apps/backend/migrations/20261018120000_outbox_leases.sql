@@ -0,0 +1,11 @@
+-- Modify "outbox_messages" table
+ALTER TABLE "outbox_messages" ADD COLUMN "leased_until" timestamptz NULL;
+-- Create index "idx_outbox_messages_delivered_at" to table: "outbox_messages"
+CREATE INDEX "idx_outbox_messages_delivered_at" ON "outbox_messages" ("delivered_at");
+-- Create "outbox_deliveries" table
+CREATE TABLE "outbox_deliveries" (
+  "message_id" uuid NOT NULL,
+  "sink" character varying(64) NOT NULL,
+  "delivered_at" timestamptz NOT NULL,
+  PRIMARY KEY ("message_id", "sink")
+);
@@ -0,0 +1,14 @@
+-- Drop "user_roles" table
+DROP TABLE "user_roles";
+-- Drop "user_profiles" table
+DROP TABLE "user_profiles";
+-- Drop "users" table
+DROP TABLE "users";
+-- Drop "role_permissions" table
+DROP TABLE "role_permissions";
+-- Drop "roles" table
+DROP TABLE "roles";
+-- Drop "sessions" table
+DROP TABLE "sessions";
+-- Drop "permissions" table
+DROP TABLE "permissions";
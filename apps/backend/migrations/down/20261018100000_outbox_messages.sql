@@ -0,0 +1,2 @@
+-- Drop "outbox_messages" table
+DROP TABLE "outbox_messages";
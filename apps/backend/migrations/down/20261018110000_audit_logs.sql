@@ -0,0 +1,2 @@
+-- Drop "audit_logs" table
+DROP TABLE "audit_logs";
@@ -0,0 +1,6 @@
+-- Drop "outbox_deliveries" table
+DROP TABLE "outbox_deliveries";
+-- Drop index "idx_outbox_messages_delivered_at" from table: "outbox_messages"
+DROP INDEX "idx_outbox_messages_delivered_at";
+-- Modify "outbox_messages" table
+ALTER TABLE "outbox_messages" DROP COLUMN "leased_until";
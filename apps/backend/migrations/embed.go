@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS holds the migration files, atlas.sum and the down scripts reverting them
+//
+//go:embed *.sql atlas.sum down/*.sql
+var FS embed.FS
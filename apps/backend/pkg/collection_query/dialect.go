@@ -0,0 +1,140 @@
+package collectionquery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	dialectPostgres  = "postgres"
+	dialectMySQL     = "mysql"
+	dialectSQLServer = "sqlserver"
+	dialectSQLite    = "sqlite"
+)
+
+// ErrUnsupportedOperator is returned when a filter or ordering has no
+// equivalent in the SQL dialect of the database being queried
+var ErrUnsupportedOperator = errors.New("unsupported query operator")
+
+// sqlDialect renders the parts of a collection query whose syntax differs
+// between databases
+type sqlDialect struct {
+	db   *gorm.DB
+	name string
+}
+
+func dialectOf(db *gorm.DB) sqlDialect {
+	return sqlDialect{db: db, name: db.Dialector.Name()}
+}
+
+func (d sqlDialect) unsupported(feature string) error {
+	return fmt.Errorf("%w: %s is not supported on %s", ErrUnsupportedOperator, feature, d.name)
+}
+
+// column quotes table.column with the identifier quotes of the dialect
+func (d sqlDialect) column(table, column string) string {
+	return d.db.Statement.Quote(clause.Column{Table: table, Name: column})
+}
+
+// jsonText extracts the value at path from a JSON column as text
+func (d sqlDialect) jsonText(column string, path []string) (string, error) {
+	switch d.name {
+	case dialectPostgres:
+		expr := column
+		for _, key := range path[:len(path)-1] {
+			expr += fmt.Sprintf(" -> '%s'", key)
+		}
+		return fmt.Sprintf("%s ->> '%s'", expr, path[len(path)-1]), nil
+	case dialectMySQL:
+		return fmt.Sprintf("%s ->> '$.%s'", column, strings.Join(path, ".")), nil
+	case dialectSQLServer:
+		return fmt.Sprintf("JSON_VALUE(%s, '$.%s')", column, strings.Join(path, ".")), nil
+	case dialectSQLite:
+		return fmt.Sprintf("json_extract(%s, '$.%s')", column, strings.Join(path, ".")), nil
+	default:
+		return "", d.unsupported("JSON path filtering")
+	}
+}
+
+// contains tests whether a JSON or array column contains the bound value
+func (d sqlDialect) contains(expr string) (string, error) {
+	switch d.name {
+	case dialectPostgres:
+		return fmt.Sprintf("%s @> ?", expr), nil
+	case dialectMySQL:
+		return fmt.Sprintf("JSON_CONTAINS(%s, ?)", expr), nil
+	default:
+		return "", d.unsupported("containment filtering")
+	}
+}
+
+// castJSON converts a text expression to a JSON value the dialect can search
+func (d sqlDialect) castJSON(expr string) (string, error) {
+	switch d.name {
+	case dialectPostgres:
+		return fmt.Sprintf("(%s)::jsonb", expr), nil
+	case dialectMySQL:
+		return fmt.Sprintf("CAST(%s AS JSON)", expr), nil
+	default:
+		return "", d.unsupported("containment filtering")
+	}
+}
+
+// iLike matches case-insensitively, lowering both sides where ILIKE is missing
+func (d sqlDialect) iLike(expr string) string {
+	if d.name == dialectPostgres {
+		return fmt.Sprintf("%s ILIKE ?", expr)
+	}
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", expr)
+}
+
+// arrayComparison renders = ANY(?) and = ALL(?), which need Postgres arrays
+func (d sqlDialect) arrayComparison(expr string, op FilterOperators) (string, error) {
+	if d.name != dialectPostgres {
+		return "", d.unsupported(fmt.Sprintf("the %s operator", op))
+	}
+	if op == All {
+		return fmt.Sprintf("%s = ALL(?)", expr), nil
+	}
+	return fmt.Sprintf("%s = ANY(?)", expr), nil
+}
+
+// countRows counts the rows of table in a HAVING clause
+func (d sqlDialect) countRows(table string) string {
+	if d.name == dialectPostgres {
+		return fmt.Sprintf("COUNT(%s.*)", table)
+	}
+	return "COUNT(*)"
+}
+
+// orderBy renders an ORDER BY item, emulating NULLS FIRST/LAST with a
+// leading IS NULL sort where the dialect lacks it
+func (d sqlDialect) orderBy(expr, direction string, nulls *NullsOrder) string {
+	orderStr := expr + " " + direction
+	if nulls == nil {
+		return orderStr
+	}
+
+	// SQLite supports NULLS FIRST/LAST since 3.30
+	if d.name == dialectPostgres || d.name == dialectSQLite {
+		switch *nulls {
+		case NullsFirst:
+			return orderStr + " NULLS FIRST"
+		case NullsLast:
+			return orderStr + " NULLS LAST"
+		}
+		return orderStr
+	}
+
+	switch *nulls {
+	case NullsFirst:
+		return fmt.Sprintf("CASE WHEN %s IS NULL THEN 0 ELSE 1 END, %s", expr, orderStr)
+	case NullsLast:
+		return fmt.Sprintf("CASE WHEN %s IS NULL THEN 1 ELSE 0 END, %s", expr, orderStr)
+	}
+	return orderStr
+}
@@ -6,10 +6,16 @@ import (
 	"sync"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/schema"
 )
 
-type QueryConstructor[T any] struct{}
+type QueryConstructor[T any] struct {
+	// Lock, when set, adds a row locking clause (e.g. FOR UPDATE) to the data
+	// query. The count query is never locked, as Postgres rejects locking
+	// clauses on aggregates.
+	Lock *clause.Locking
+}
 
 func (qc *QueryConstructor[T]) ConstructQuery(
 	db *gorm.DB,
@@ -32,6 +38,7 @@ func (qc *QueryConstructor[T]) ConstructQuery(
 	}
 
 	tableName := sch.Table
+	dialect := dialectOf(qb)
 
 	// Remove empty filters
 	query = qc.removeEmptyFilter(query)
@@ -46,7 +53,7 @@ func (qc *QueryConstructor[T]) ConstructQuery(
 	}
 
 	// Apply Where Clauses
-	qb = qc.applyWhereConditions(tableName, qb, query.Where)
+	qb = qc.applyWhereConditions(dialect, tableName, qb, query.Where)
 
 	// Apply GroupBy
 	if len(query.GroupBy) > 0 {
@@ -57,12 +64,12 @@ func (qc *QueryConstructor[T]) ConstructQuery(
 
 	// Apply HAVING
 	if len(query.Having) > 0 {
-		qb = qc.applyHavingConditions(tableName, qb, query.Having)
+		qb = qc.applyHavingConditions(dialect, tableName, qb, query.Having)
 	}
 
 	// Apply ORDER BY
 	for _, order := range query.OrderBy {
-		qb = qc.applyOrder(tableName, qb, order)
+		qb = qc.applyOrder(dialect, tableName, qb, order)
 	}
 
 	// Apply SKIP (offset)
@@ -144,8 +151,10 @@ func (qc *QueryConstructor[T]) removeFilter(query CollectionQuery, key string) C
 	return query
 }
 
-// applyWhereConditions applies WHERE clauses with OR/AND logic
+// applyWhereConditions applies WHERE clauses with OR/AND logic. A clause the
+// dialect cannot express is added to qb as an ErrUnsupportedOperator error.
 func (qc *QueryConstructor[T]) applyWhereConditions(
+	dialect sqlDialect,
 	tableName string,
 	qb *gorm.DB,
 	whereClauses [][]Where,
@@ -158,7 +167,11 @@ func (qc *QueryConstructor[T]) applyWhereConditions(
 		// Build OR conditions within this AND group
 		qb = qb.Where(func(tx *gorm.DB) *gorm.DB {
 			for i, clause := range orGroup {
-				condition, args := qc.buildFilterCondition(tableName, clause)
+				condition, args, err := qc.buildFilterCondition(dialect, tableName, clause)
+				if err != nil {
+					_ = tx.AddError(err)
+					return tx
+				}
 
 				if i == 0 {
 					tx = tx.Where(condition, args...)
@@ -173,73 +186,56 @@ func (qc *QueryConstructor[T]) applyWhereConditions(
 }
 
 // buildFilterCondition builds the WHERE condition string and returns args
-func (qc *QueryConstructor[T]) buildFilterCondition(tableName string, clause Where) (string, []interface{}) {
+func (qc *QueryConstructor[T]) buildFilterCondition(
+	dialect sqlDialect,
+	tableName string,
+	clause Where,
+) (string, []interface{}, error) {
 	column := clause.Column
 	op := clause.Operator
 	value := clause.Value
 
-	var queryCondition string
-
 	if op == ArrayFilter {
-		if strings.Contains(column, "->>") {
-			parts := strings.SplitN(column, "->>", 2)
-			mainColumn := parts[0]
-			nestedColumn := parts[1]
-			queryCondition = fmt.Sprintf(`("%s"."%s"->>'%s')::jsonb @> ?`, tableName, mainColumn, nestedColumn)
-		} else {
-			queryCondition = fmt.Sprintf(`"%s"."%s" @> ?`, tableName, column)
+		if mainColumn, nestedColumn, ok := strings.Cut(column, "->>"); ok {
+			expr, err := dialect.jsonText(dialect.column(tableName, mainColumn), []string{nestedColumn})
+			if err != nil {
+				return "", nil, err
+			}
+			if expr, err = dialect.castJSON(expr); err != nil {
+				return "", nil, err
+			}
+			condition, err := dialect.contains(expr)
+			return condition, []interface{}{value}, err
 		}
 
-		return queryCondition, []interface{}{value}
+		condition, err := dialect.contains(dialect.column(tableName, column))
+		return condition, []interface{}{value}, err
 	}
 
-	// Handler relation columns
-	if strings.Contains(column, ".") {
-		parts := strings.SplitN(column, ".", 2)
-		relation := parts[0]
-		field := parts[1]
-		if strings.Contains(field, "->>") {
-			fieldParts := strings.SplitN(field, "->>", 2)
-			mainColumn := fieldParts[0]
-			nestedColumn := fieldParts[1]
-			queryCondition = fmt.Sprintf(`"%s"."%s" ->> '%s'`, relation, mainColumn, nestedColumn)
-		} else if strings.Contains(field, "@>") {
-			fieldParts := strings.SplitN(field, "@>", 2)
-			mainColumn := fieldParts[0]
-			queryCondition = fmt.Sprintf(`"%s"."%s" @>`, relation, mainColumn)
-		} else {
-			queryCondition = fmt.Sprintf(`"%s"."%s"`, relation, field)
-		}
-		return qc.applyOperators(queryCondition, op, value)
+	// Handle relation columns
+	table := tableName
+	if relation, field, ok := strings.Cut(column, "."); ok {
+		table, column = relation, field
 	}
 
-	// Handle @> operator for main entity
-	if strings.Contains(column, "@>") {
-		parts := strings.SplitN(column, "@>", 2)
-		mainColumn := parts[0]
-		queryCondition = fmt.Sprintf(`"%s"."%s" @>`, tableName, mainColumn)
-		return qc.applyOperators(queryCondition, op, value)
+	// Handle @> operator, which tests containment whatever the operator
+	if mainColumn, _, ok := strings.Cut(column, "@>"); ok {
+		condition, err := dialect.contains(dialect.column(table, mainColumn))
+		return condition, []interface{}{value}, err
 	}
 
-	// Handle JSON field queries (->>)
-	if strings.Contains(column, "->>") {
-		parts := strings.SplitN(column, "->>", 2)
-		mainColumn := parts[0]
-		nestedColumn := parts[1]
-		if strings.Contains(mainColumn, "->") {
-			subParts := strings.SplitN(mainColumn, "->", 2)
-			main := subParts[0]
-			path := subParts[1]
-			queryCondition = fmt.Sprintf(`"%s"."%s" -> '%s' ->> '%s'`, tableName, main, path, path)
-		} else {
-			queryCondition = fmt.Sprintf(`"%s"."%s" ->> '%s'`, tableName, mainColumn, nestedColumn)
+	// Handle JSON field queries (->>), optionally nested with ->
+	if mainColumn, nestedColumn, ok := strings.Cut(column, "->>"); ok {
+		path := strings.Split(mainColumn, "->")
+		expr, err := dialect.jsonText(dialect.column(table, path[0]), append(path[1:], nestedColumn))
+		if err != nil {
+			return "", nil, err
 		}
-		return qc.applyOperators(queryCondition, op, value)
+		return qc.applyOperators(dialect, expr, op, value)
 	}
 
 	// Handle regular columns
-	queryCondition = fmt.Sprintf(`"%s"."%s"`, tableName, column)
-	return qc.applyOperators(queryCondition, op, value)
+	return qc.applyOperators(dialect, dialect.column(table, column), op, value)
 }
 
 var operatorFormat = map[FilterOperators]string{
@@ -248,51 +244,56 @@ var operatorFormat = map[FilterOperators]string{
 	LessThan:             "%s < ?",
 	GreaterThanOrEqualTo: "%s >= ?",
 	LessThanOrEqualTo:    "%s <= ?",
-	All:                  "%s = ALL(?)",
-	Any:                  "%s = ANY(?)",
 	Like:                 "%s LIKE ?",
-	ILike:                "%s ILIKE ?",
-	ArrayFilter:          "%s @> ?",
-	ArrayContains:        "%s @> ?",
 }
 
 func (qc *QueryConstructor[T]) applyOperators(
+	dialect sqlDialect,
 	queryCondition string,
 	op FilterOperators,
 	value string,
-) (string, []interface{}) {
+) (string, []interface{}, error) {
 	if format, ok := operatorFormat[op]; ok {
 		var arg interface{} = value
-		if op == Like || op == ILike {
+		if op == Like {
 			arg = fmt.Sprintf("%%%s%%", value)
 		}
-		return fmt.Sprintf(format, queryCondition), []interface{}{arg}
+		return fmt.Sprintf(format, queryCondition), []interface{}{arg}, nil
 	}
 
 	switch op {
+	case ILike:
+		return dialect.iLike(queryCondition), []interface{}{fmt.Sprintf("%%%s%%", value)}, nil
+	case All, Any:
+		condition, err := dialect.arrayComparison(queryCondition, op)
+		return condition, []interface{}{value}, err
+	case ArrayFilter, ArrayContains:
+		condition, err := dialect.contains(queryCondition)
+		return condition, []interface{}{value}, err
 	case Between:
 		parts := strings.Split(value, ",")
 		if len(parts) == 2 {
-			return fmt.Sprintf("%s BETWEEN ? AND ?", queryCondition), []interface{}{parts[0], parts[1]}
+			return fmt.Sprintf("%s BETWEEN ? AND ?", queryCondition), []interface{}{parts[0], parts[1]}, nil
 		}
-		return fmt.Sprintf("%s = ?", queryCondition), []interface{}{value}
+		return fmt.Sprintf("%s = ?", queryCondition), []interface{}{value}, nil
 	case In, NotIn:
 		values := strings.Split(value, ",")
 		operator := "IN"
 		if op == NotIn {
 			operator = "NOT IN"
 		}
-		return fmt.Sprintf("%s %s (?)", queryCondition, operator), []interface{}{values}
+		return fmt.Sprintf("%s %s (?)", queryCondition, operator), []interface{}{values}, nil
 	case IsNull:
-		return fmt.Sprintf("%s IS NULL", queryCondition), nil
+		return fmt.Sprintf("%s IS NULL", queryCondition), nil, nil
 	case IsNotNull:
-		return fmt.Sprintf("%s IS NOT NULL", queryCondition), nil
+		return fmt.Sprintf("%s IS NOT NULL", queryCondition), nil, nil
 	default:
-		return fmt.Sprintf("%s %s ?", queryCondition, op), []interface{}{value}
+		return fmt.Sprintf("%s %s ?", queryCondition, op), []interface{}{value}, nil
 	}
 }
 
 func (qc *QueryConstructor[T]) applyHavingConditions(
+	dialect sqlDialect,
 	tableName string,
 	db *gorm.DB,
 	conditions [][]Where,
@@ -304,7 +305,7 @@ func (qc *QueryConstructor[T]) applyHavingConditions(
 
 		db = db.Having(func(tx *gorm.DB) *gorm.DB {
 			for i, clause := range orGroup {
-				condition, args := qc.buildHavingCondition(tableName, clause)
+				condition, args := qc.buildHavingCondition(dialect, tableName, clause)
 				if i == 0 {
 					tx = tx.Having(condition, args...)
 				} else {
@@ -319,64 +320,50 @@ func (qc *QueryConstructor[T]) applyHavingConditions(
 
 // buildHavingCondition builds HAVING condition with COUNT
 func (qc *QueryConstructor[T]) buildHavingCondition(
+	dialect sqlDialect,
 	tableName string,
 	clause Where,
 ) (string, []interface{}) {
 	column := clause.Column
 	op := clause.Operator
 	value := clause.Value
+	countRows := dialect.countRows(tableName)
 
 	switch op {
 	case Between:
 		parts := strings.Split(value, ",")
 		if len(parts) == 2 {
-			return fmt.Sprintf("COUNT(%s.*) BETWEEN ? AND ?", tableName), []interface{}{parts[0], parts[1]}
+			return fmt.Sprintf("%s BETWEEN ? AND ?", countRows), []interface{}{parts[0], parts[1]}
 		}
-		return fmt.Sprintf("COUNT(%s.*) = ?", tableName), []interface{}{value}
+		return fmt.Sprintf("%s = ?", countRows), []interface{}{value}
 	case In:
 		values := strings.Split(value, ",")
-		return fmt.Sprintf("COUNT(%s.*) IN ?", tableName), []interface{}{values}
+		return fmt.Sprintf("%s IN ?", countRows), []interface{}{values}
 	case Like:
-		return fmt.Sprintf("COUNT(%s.*) LIKE ?", tableName), []interface{}{fmt.Sprintf("%%%s%%", value)}
-	case Any, All:
-		return fmt.Sprintf("COUNT(%s.%s) %s ?", tableName, column, op), []interface{}{value}
+		return fmt.Sprintf("%s LIKE ?", countRows), []interface{}{fmt.Sprintf("%%%s%%", value)}
 	default:
-		return fmt.Sprintf("COUNT(%s.%s) %s ?", tableName, column, op), []interface{}{value}
+		return fmt.Sprintf("COUNT(%s) %s ?", dialect.column(tableName, column), op), []interface{}{value}
 	}
 }
 
 // applyOrder applies ordering
 func (qc *QueryConstructor[T]) applyOrder(
+	dialect sqlDialect,
 	tableName string,
 	db *gorm.DB,
 	order Order,
 ) *gorm.DB {
-	column := order.Column
-	var orderStr string
-
-	if strings.Contains(column, ".") {
-		parts := strings.SplitN(column, ".", 2)
-		orderStr = fmt.Sprintf(`"%s"."%s"`, parts[0], parts[1])
-	} else {
-		orderStr = fmt.Sprintf(`"%s"."%s"`, tableName, column)
+	table, column := tableName, order.Column
+	if relation, field, ok := strings.Cut(order.Column, "."); ok {
+		table, column = relation, field
 	}
 
+	direction := string(Ascending)
 	if order.Direction != nil {
-		orderStr += " " + string(*order.Direction)
-	} else {
-		orderStr += " ASC"
-	}
-
-	if order.Nulls != nil {
-		switch *order.Nulls {
-		case NullsFirst:
-			orderStr += " NULLS FIRST"
-		case NullsLast:
-			orderStr += " NULLS LAST"
-		}
+		direction = string(*order.Direction)
 	}
 
-	return db.Order(orderStr)
+	return db.Order(dialect.orderBy(dialect.column(table, column), direction, order.Nulls))
 }
 
 func (qc *QueryConstructor[T]) applyInclude(
@@ -443,6 +430,9 @@ func (qc *QueryConstructor[T]) Find(
 	}
 
 	// Fetch the data with pagination applied
+	if qc.Lock != nil {
+		qb = qb.Clauses(*qc.Lock)
+	}
 	var items []*T
 	if err := qb.Find(&items).Error; err != nil {
 		return nil, err
@@ -0,0 +1,36 @@
+package collectionquery
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// QueryParams binds the encoded CollectionQuery parameters from a request's
+// query string, using the same keys as EncodeColllectionQuery.
+type QueryParams struct {
+	Select   string `query:"s"  doc:"Comma-separated columns to select"`
+	Where    string `query:"w"  doc:"Encoded filter groups (column_:operator_:value, _, for OR, _| for AND)"`
+	Take     int    `query:"t"  minimum:"0" default:"10" doc:"Number of records to return"`
+	Skip     int    `query:"sk" minimum:"0" default:"0"  doc:"Number of records to skip"`
+	OrderBy  string `query:"o"  doc:"Comma-separated column:direction pairs"`
+	Includes string `query:"i"  doc:"Comma-separated relations to include"`
+}
+
+// ToCollectionQuery decodes the bound parameters into a CollectionQuery
+func (p QueryParams) ToCollectionQuery() (CollectionQuery, error) {
+	values := url.Values{}
+	set := func(key, val string) {
+		if val != "" {
+			values.Set(key, val)
+		}
+	}
+
+	set("s", p.Select)
+	set("w", p.Where)
+	set("t", strconv.Itoa(p.Take))
+	set("sk", strconv.Itoa(p.Skip))
+	set("o", p.OrderBy)
+	set("i", p.Includes)
+
+	return DecodeCollectionQuery(values.Encode())
+}
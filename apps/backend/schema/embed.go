@@ -0,0 +1,9 @@
+package schema
+
+import _ "embed"
+
+// Snapshot is the DDL of every registered entity when schema.sql was last
+// generated
+//
+//go:embed schema.sql
+var Snapshot string
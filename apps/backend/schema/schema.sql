@@ -22,8 +22,20 @@ CREATE INDEX IF NOT EXISTS "idx_role_permissions_role_id" ON "role_permissions"
 CREATE TABLE "user_roles" ("user_id" uuid NOT NULL,"role_id" uuid NOT NULL);
 CREATE INDEX IF NOT EXISTS "idx_user_roles_role_id" ON "user_roles" ("role_id");
 CREATE INDEX IF NOT EXISTS "idx_user_roles_user_id" ON "user_roles" ("user_id");
-ALTER TABLE "user_profiles" ADD CONSTRAINT "fk_users_profile" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE SET NULL ON UPDATE CASCADE;
+CREATE TABLE "outbox_messages" ("id" uuid DEFAULT uuid_generate_v4(),"created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,"updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,"deleted_at" timestamptz,"topic" text NOT NULL,"aggregate_type" text NOT NULL DEFAULT '',"aggregate_id" uuid,"payload" jsonb NOT NULL,"status" varchar(16) NOT NULL DEFAULT 'pending',"attempts" bigint NOT NULL DEFAULT 0,"next_attempt_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,"leased_until" timestamptz,"last_error" text,"delivered_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_outbox_messages_delivered_at" ON "outbox_messages" ("delivered_at");
+CREATE INDEX IF NOT EXISTS "idx_outbox_messages_due" ON "outbox_messages" ("status","next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_outbox_messages_topic" ON "outbox_messages" ("topic");
+CREATE INDEX IF NOT EXISTS "idx_outbox_messages_deleted_at" ON "outbox_messages" ("deleted_at");
+CREATE TABLE "outbox_deliveries" ("message_id" uuid,"sink" varchar(64),"delivered_at" timestamptz NOT NULL,PRIMARY KEY ("message_id","sink"));
+CREATE TABLE "audit_logs" ("id" uuid DEFAULT uuid_generate_v4(),"created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,"updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,"deleted_at" timestamptz,"actor_id" uuid,"tenant_id" text NOT NULL DEFAULT '',"entity_type" text NOT NULL,"entity_id" uuid NOT NULL,"action" varchar(16) NOT NULL,"changes" jsonb NOT NULL,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_entity" ON "audit_logs" ("entity_type","entity_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_tenant_id" ON "audit_logs" ("tenant_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_actor_id" ON "audit_logs" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_deleted_at" ON "audit_logs" ("deleted_at");
+ALTER TABLE "user_profiles" ADD CONSTRAINT "fk_users_profile" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "sessions" ADD CONSTRAINT "fk_users_sessions" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE;
 ALTER TABLE "role_permissions" ADD CONSTRAINT "fk_permissions_roles" FOREIGN KEY ("permission_id") REFERENCES "permissions"("id");
 ALTER TABLE "role_permissions" ADD CONSTRAINT "fk_roles_permissions" FOREIGN KEY ("role_id") REFERENCES "roles"("id");
 ALTER TABLE "user_roles" ADD CONSTRAINT "fk_roles_users" FOREIGN KEY ("role_id") REFERENCES "roles"("id");
-ALTER TABLE "user_roles" ADD CONSTRAINT "fk_users_roles" FOREIGN KEY ("user_id") REFERENCES "users"("id");
+ALTER TABLE "user_roles" ADD CONSTRAINT "fk_users_roles" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE;